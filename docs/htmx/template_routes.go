@@ -27,10 +27,12 @@ package hypertext
 import (
 	"bytes"
 	"cmp"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"sync"
 )
 
 type RoutesReceiver interface {
@@ -43,87 +45,23 @@ func TemplateRoutes(mux *http.ServeMux, receiver RoutesReceiver) {
 	mux.HandleFunc("/", func(response http.ResponseWriter, request *http.Request) {
 		result := receiver.Count()
 		td := newTemplateData(receiver, response, request, result, true, nil)
-		buf := bytes.NewBuffer(nil)
-		if err := templates.ExecuteTemplate(buf, "/ Count()", td); err != nil {
-			slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-			http.Error(response, "failed to render page", http.StatusInternalServerError)
-			return
-		}
-		statusCode := cmp.Or(td.statusCode, http.StatusOK)
-		if td.redirectURL != "" {
-			http.Redirect(response, request, td.redirectURL, statusCode)
-			return
-		}
-		if contentType := response.Header().Get("content-type"); contentType == "" {
-			response.Header().Set("content-type", "text/html; charset=utf-8")
-		}
-		response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-		response.WriteHeader(statusCode)
-		_, _ = buf.WriteTo(response)
+		td.render("/ Count()", "Count", http.StatusOK)
 	})
 	mux.HandleFunc("POST /count", func(response http.ResponseWriter, request *http.Request) {
 		result := struct {
 		}{}
-		buf := bytes.NewBuffer(nil)
 		td := newTemplateData(receiver, response, request, result, true, nil)
-		if err := templates.ExecuteTemplate(buf, "POST /count", td); err != nil {
-			slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-			http.Error(response, "failed to render page", http.StatusInternalServerError)
-			return
-		}
-		statusCode := cmp.Or(td.statusCode, http.StatusOK)
-		if td.redirectURL != "" {
-			http.Redirect(response, request, td.redirectURL, statusCode)
-			return
-		}
-		if contentType := response.Header().Get("content-type"); contentType == "" {
-			response.Header().Set("content-type", "text/html; charset=utf-8")
-		}
-		response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-		response.WriteHeader(statusCode)
-		_, _ = buf.WriteTo(response)
+		td.render("POST /count", "", http.StatusOK)
 	})
 	mux.HandleFunc("/decrement-count", func(response http.ResponseWriter, request *http.Request) {
 		result := receiver.Decrement()
 		td := newTemplateData(receiver, response, request, result, true, nil)
-		buf := bytes.NewBuffer(nil)
-		if err := templates.ExecuteTemplate(buf, "/decrement-count Decrement()", td); err != nil {
-			slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-			http.Error(response, "failed to render page", http.StatusInternalServerError)
-			return
-		}
-		statusCode := cmp.Or(td.statusCode, http.StatusOK)
-		if td.redirectURL != "" {
-			http.Redirect(response, request, td.redirectURL, statusCode)
-			return
-		}
-		if contentType := response.Header().Get("content-type"); contentType == "" {
-			response.Header().Set("content-type", "text/html; charset=utf-8")
-		}
-		response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-		response.WriteHeader(statusCode)
-		_, _ = buf.WriteTo(response)
+		td.render("/decrement-count Decrement()", "Decrement", http.StatusOK)
 	})
 	mux.HandleFunc("/increment-count", func(response http.ResponseWriter, request *http.Request) {
 		result := receiver.Increment()
 		td := newTemplateData(receiver, response, request, result, true, nil)
-		buf := bytes.NewBuffer(nil)
-		if err := templates.ExecuteTemplate(buf, "/increment-count Increment()", td); err != nil {
-			slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-			http.Error(response, "failed to render page", http.StatusInternalServerError)
-			return
-		}
-		statusCode := cmp.Or(td.statusCode, http.StatusOK)
-		if td.redirectURL != "" {
-			http.Redirect(response, request, td.redirectURL, statusCode)
-			return
-		}
-		if contentType := response.Header().Get("content-type"); contentType == "" {
-			response.Header().Set("content-type", "text/html; charset=utf-8")
-		}
-		response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-		response.WriteHeader(statusCode)
-		_, _ = buf.WriteTo(response)
+		td.render("/increment-count Increment()", "Increment", http.StatusOK)
 	})
 }
 
@@ -189,6 +127,52 @@ func (data *TemplateData[T]) Redirect(url string, code int) (*TemplateData[T], e
 	return data.StatusCode(code), nil
 }
 
+func (data *TemplateData[T]) errorStatusCode() int {
+	var sc interface {
+		StatusCode() int
+	}
+	if errors.As(data.err, &sc) {
+		return sc.StatusCode()
+	}
+	return 0
+}
+
+func (data *TemplateData[T]) render(name, receiverMethod string, statusCode int) {
+	response, request := data.response, data.request
+	buf := templateDataBufferPool.Get().(*bytes.Buffer)
+	defer putTemplateDataBuffer(buf)
+	if err := templates.ExecuteTemplate(buf, name, data); err != nil {
+		slog.ErrorContext(request.Context(), "failed to render page", slog.String("method", request.Method), slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("template", name), slog.String("receiver_method", receiverMethod), slog.Int("status_code", cmp.Or(data.statusCode, data.errorStatusCode(), statusCode)), slog.String("error", err.Error()))
+		http.Error(response, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+	statusCode = cmp.Or(data.statusCode, data.errorStatusCode(), statusCode)
+	if data.redirectURL != "" {
+		http.Redirect(response, request, data.redirectURL, statusCode)
+		return
+	}
+	if contentType := response.Header().Get("content-type"); contentType == "" {
+		response.Header().Set("content-type", "text/html; charset=utf-8")
+	}
+	response.Header().Set("content-length", strconv.Itoa(buf.Len()))
+	response.WriteHeader(statusCode)
+	if request.Method != http.MethodHead {
+		_, _ = buf.WriteTo(response)
+	}
+}
+
+var templateDataBufferPool = sync.Pool{New: func() any {
+	return new(bytes.Buffer)
+}}
+
+func putTemplateDataBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > 64<<10 {
+		return
+	}
+	buf.Reset()
+	templateDataBufferPool.Put(buf)
+}
+
 type TemplateRoutePaths struct {
 }
 
@@ -34,6 +34,7 @@ import (
 	"net/http"
 	"path"
 	"strconv"
+	"sync"
 )
 
 type RoutesReceiver interface {
@@ -48,23 +49,7 @@ func TemplateRoutes(mux *http.ServeMux, receiver RoutesReceiver) {
 		if err != nil {
 			var zv Row
 			rd := newTemplateData(receiver, response, request, zv, false, err)
-			buf := bytes.NewBuffer(nil)
-			if err := templates.ExecuteTemplate(buf, "PATCH /fruits/{id} SubmitFormEditRow(id, form)", rd); err != nil {
-				slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-				http.Error(response, "failed to render page", http.StatusInternalServerError)
-				return
-			}
-			sc := cmp.Or(rd.statusCode, http.StatusBadRequest)
-			if rd.redirectURL != "" {
-				http.Redirect(response, request, rd.redirectURL, sc)
-				return
-			}
-			if contentType := response.Header().Get("content-type"); contentType == "" {
-				response.Header().Set("content-type", "text/html; charset=utf-8")
-			}
-			response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-			response.WriteHeader(sc)
-			_, _ = buf.WriteTo(response)
+			rd.render("PATCH /fruits/{id} SubmitFormEditRow(id, form)", "SubmitFormEditRow", http.StatusBadRequest)
 			return
 		}
 		id := idParsed
@@ -75,45 +60,13 @@ func TemplateRoutes(mux *http.ServeMux, receiver RoutesReceiver) {
 			if err != nil {
 				var zv Row
 				rd := newTemplateData(receiver, response, request, zv, false, err)
-				buf := bytes.NewBuffer(nil)
-				if err := templates.ExecuteTemplate(buf, "PATCH /fruits/{id} SubmitFormEditRow(id, form)", rd); err != nil {
-					slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-					http.Error(response, "failed to render page", http.StatusInternalServerError)
-					return
-				}
-				sc := cmp.Or(rd.statusCode, http.StatusBadRequest)
-				if rd.redirectURL != "" {
-					http.Redirect(response, request, rd.redirectURL, sc)
-					return
-				}
-				if contentType := response.Header().Get("content-type"); contentType == "" {
-					response.Header().Set("content-type", "text/html; charset=utf-8")
-				}
-				response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-				response.WriteHeader(sc)
-				_, _ = buf.WriteTo(response)
+				rd.render("PATCH /fruits/{id} SubmitFormEditRow(id, form)", "SubmitFormEditRow", http.StatusBadRequest)
 				return
 			}
 			if value < 0 {
 				var zv Row
 				rd := newTemplateData(receiver, response, request, zv, false, errors.New("count must not be less than 0"))
-				buf := bytes.NewBuffer(nil)
-				if err := templates.ExecuteTemplate(buf, "PATCH /fruits/{id} SubmitFormEditRow(id, form)", rd); err != nil {
-					slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-					http.Error(response, "failed to render page", http.StatusInternalServerError)
-					return
-				}
-				sc := cmp.Or(rd.statusCode, http.StatusBadRequest)
-				if rd.redirectURL != "" {
-					http.Redirect(response, request, rd.redirectURL, sc)
-					return
-				}
-				if contentType := response.Header().Get("content-type"); contentType == "" {
-					response.Header().Set("content-type", "text/html; charset=utf-8")
-				}
-				response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-				response.WriteHeader(sc)
-				_, _ = buf.WriteTo(response)
+				rd.render("PATCH /fruits/{id} SubmitFormEditRow(id, form)", "SubmitFormEditRow", http.StatusBadRequest)
 				return
 			}
 			form.Value = value
@@ -122,66 +75,18 @@ func TemplateRoutes(mux *http.ServeMux, receiver RoutesReceiver) {
 		if err != nil {
 			var zv Row
 			rd := newTemplateData(receiver, response, request, zv, false, err)
-			buf := bytes.NewBuffer(nil)
-			if err := templates.ExecuteTemplate(buf, "PATCH /fruits/{id} SubmitFormEditRow(id, form)", rd); err != nil {
-				slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-				http.Error(response, "failed to render page", http.StatusInternalServerError)
-				return
-			}
-			sc := cmp.Or(rd.statusCode, http.StatusInternalServerError)
-			if rd.redirectURL != "" {
-				http.Redirect(response, request, rd.redirectURL, sc)
-				return
-			}
-			if contentType := response.Header().Get("content-type"); contentType == "" {
-				response.Header().Set("content-type", "text/html; charset=utf-8")
-			}
-			response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-			response.WriteHeader(sc)
-			_, _ = buf.WriteTo(response)
+			rd.render("PATCH /fruits/{id} SubmitFormEditRow(id, form)", "SubmitFormEditRow", http.StatusInternalServerError)
 			return
 		}
 		td := newTemplateData(receiver, response, request, result, true, nil)
-		buf := bytes.NewBuffer(nil)
-		if err := templates.ExecuteTemplate(buf, "PATCH /fruits/{id} SubmitFormEditRow(id, form)", td); err != nil {
-			slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-			http.Error(response, "failed to render page", http.StatusInternalServerError)
-			return
-		}
-		statusCode := cmp.Or(td.statusCode, http.StatusOK)
-		if td.redirectURL != "" {
-			http.Redirect(response, request, td.redirectURL, statusCode)
-			return
-		}
-		if contentType := response.Header().Get("content-type"); contentType == "" {
-			response.Header().Set("content-type", "text/html; charset=utf-8")
-		}
-		response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-		response.WriteHeader(statusCode)
-		_, _ = buf.WriteTo(response)
+		td.render("PATCH /fruits/{id} SubmitFormEditRow(id, form)", "SubmitFormEditRow", http.StatusOK)
 	})
 	mux.HandleFunc("GET /fruits/{id}/edit", func(response http.ResponseWriter, request *http.Request) {
 		idParsed, err := strconv.Atoi(request.PathValue("id"))
 		if err != nil {
 			var zv Row
 			rd := newTemplateData(receiver, response, request, zv, false, err)
-			buf := bytes.NewBuffer(nil)
-			if err := templates.ExecuteTemplate(buf, "GET /fruits/{id}/edit GetFormEditRow(id)", rd); err != nil {
-				slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-				http.Error(response, "failed to render page", http.StatusInternalServerError)
-				return
-			}
-			sc := cmp.Or(rd.statusCode, http.StatusBadRequest)
-			if rd.redirectURL != "" {
-				http.Redirect(response, request, rd.redirectURL, sc)
-				return
-			}
-			if contentType := response.Header().Get("content-type"); contentType == "" {
-				response.Header().Set("content-type", "text/html; charset=utf-8")
-			}
-			response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-			response.WriteHeader(sc)
-			_, _ = buf.WriteTo(response)
+			rd.render("GET /fruits/{id}/edit GetFormEditRow(id)", "GetFormEditRow", http.StatusBadRequest)
 			return
 		}
 		id := idParsed
@@ -189,87 +94,23 @@ func TemplateRoutes(mux *http.ServeMux, receiver RoutesReceiver) {
 		if err != nil {
 			var zv Row
 			rd := newTemplateData(receiver, response, request, zv, false, err)
-			buf := bytes.NewBuffer(nil)
-			if err := templates.ExecuteTemplate(buf, "GET /fruits/{id}/edit GetFormEditRow(id)", rd); err != nil {
-				slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-				http.Error(response, "failed to render page", http.StatusInternalServerError)
-				return
-			}
-			sc := cmp.Or(rd.statusCode, http.StatusInternalServerError)
-			if rd.redirectURL != "" {
-				http.Redirect(response, request, rd.redirectURL, sc)
-				return
-			}
-			if contentType := response.Header().Get("content-type"); contentType == "" {
-				response.Header().Set("content-type", "text/html; charset=utf-8")
-			}
-			response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-			response.WriteHeader(sc)
-			_, _ = buf.WriteTo(response)
+			rd.render("GET /fruits/{id}/edit GetFormEditRow(id)", "GetFormEditRow", http.StatusInternalServerError)
 			return
 		}
 		td := newTemplateData(receiver, response, request, result, true, nil)
-		buf := bytes.NewBuffer(nil)
-		if err := templates.ExecuteTemplate(buf, "GET /fruits/{id}/edit GetFormEditRow(id)", td); err != nil {
-			slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-			http.Error(response, "failed to render page", http.StatusInternalServerError)
-			return
-		}
-		statusCode := cmp.Or(td.statusCode, http.StatusOK)
-		if td.redirectURL != "" {
-			http.Redirect(response, request, td.redirectURL, statusCode)
-			return
-		}
-		if contentType := response.Header().Get("content-type"); contentType == "" {
-			response.Header().Set("content-type", "text/html; charset=utf-8")
-		}
-		response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-		response.WriteHeader(statusCode)
-		_, _ = buf.WriteTo(response)
+		td.render("GET /fruits/{id}/edit GetFormEditRow(id)", "GetFormEditRow", http.StatusOK)
 	})
 	mux.HandleFunc("GET /help", func(response http.ResponseWriter, request *http.Request) {
 		result := struct {
 		}{}
-		buf := bytes.NewBuffer(nil)
 		td := newTemplateData(receiver, response, request, result, true, nil)
-		if err := templates.ExecuteTemplate(buf, "GET /help", td); err != nil {
-			slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-			http.Error(response, "failed to render page", http.StatusInternalServerError)
-			return
-		}
-		statusCode := cmp.Or(td.statusCode, http.StatusOK)
-		if td.redirectURL != "" {
-			http.Redirect(response, request, td.redirectURL, statusCode)
-			return
-		}
-		if contentType := response.Header().Get("content-type"); contentType == "" {
-			response.Header().Set("content-type", "text/html; charset=utf-8")
-		}
-		response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-		response.WriteHeader(statusCode)
-		_, _ = buf.WriteTo(response)
+		td.render("GET /help", "", http.StatusOK)
 	})
 	mux.HandleFunc("GET /{$}", func(response http.ResponseWriter, request *http.Request) {
 		ctx := request.Context()
 		result := receiver.List(ctx)
 		td := newTemplateData(receiver, response, request, result, true, nil)
-		buf := bytes.NewBuffer(nil)
-		if err := templates.ExecuteTemplate(buf, "GET /{$} List(ctx)", td); err != nil {
-			slog.ErrorContext(request.Context(), "failed to render page", slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("error", err.Error()))
-			http.Error(response, "failed to render page", http.StatusInternalServerError)
-			return
-		}
-		statusCode := cmp.Or(td.statusCode, http.StatusOK)
-		if td.redirectURL != "" {
-			http.Redirect(response, request, td.redirectURL, statusCode)
-			return
-		}
-		if contentType := response.Header().Get("content-type"); contentType == "" {
-			response.Header().Set("content-type", "text/html; charset=utf-8")
-		}
-		response.Header().Set("content-length", strconv.Itoa(buf.Len()))
-		response.WriteHeader(statusCode)
-		_, _ = buf.WriteTo(response)
+		td.render("GET /{$} List(ctx)", "List", http.StatusOK)
 	})
 }
 
@@ -335,6 +176,52 @@ func (data *TemplateData[T]) Redirect(url string, code int) (*TemplateData[T], e
 	return data.StatusCode(code), nil
 }
 
+func (data *TemplateData[T]) errorStatusCode() int {
+	var sc interface {
+		StatusCode() int
+	}
+	if errors.As(data.err, &sc) {
+		return sc.StatusCode()
+	}
+	return 0
+}
+
+func (data *TemplateData[T]) render(name, receiverMethod string, statusCode int) {
+	response, request := data.response, data.request
+	buf := templateDataBufferPool.Get().(*bytes.Buffer)
+	defer putTemplateDataBuffer(buf)
+	if err := templates.ExecuteTemplate(buf, name, data); err != nil {
+		slog.ErrorContext(request.Context(), "failed to render page", slog.String("method", request.Method), slog.String("path", request.URL.Path), slog.String("pattern", request.Pattern), slog.String("template", name), slog.String("receiver_method", receiverMethod), slog.Int("status_code", cmp.Or(data.statusCode, data.errorStatusCode(), statusCode)), slog.String("error", err.Error()))
+		http.Error(response, "failed to render page", http.StatusInternalServerError)
+		return
+	}
+	statusCode = cmp.Or(data.statusCode, data.errorStatusCode(), statusCode)
+	if data.redirectURL != "" {
+		http.Redirect(response, request, data.redirectURL, statusCode)
+		return
+	}
+	if contentType := response.Header().Get("content-type"); contentType == "" {
+		response.Header().Set("content-type", "text/html; charset=utf-8")
+	}
+	response.Header().Set("content-length", strconv.Itoa(buf.Len()))
+	response.WriteHeader(statusCode)
+	if request.Method != http.MethodHead {
+		_, _ = buf.WriteTo(response)
+	}
+}
+
+var templateDataBufferPool = sync.Pool{New: func() any {
+	return new(bytes.Buffer)
+}}
+
+func putTemplateDataBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > 64<<10 {
+		return
+	}
+	buf.Reset()
+	templateDataBufferPool.Put(buf)
+}
+
 type TemplateRoutePaths struct {
 }
 
@@ -78,3 +78,32 @@ func TestRoutes(t *testing.T) {
 		}))
 	}
 }
+
+func BenchmarkRoutes(b *testing.B) {
+	f := new(fake.Backend)
+	f.GetFormEditRowReturns(hypertext.Row{ID: 1, Name: "a", Value: 97}, nil)
+	f.ListReturns([]hypertext.Row{
+		{ID: 1, Name: "apple", Value: 3},
+		{ID: 2, Name: "banana", Value: 5},
+		{ID: 3, Name: "cherry", Value: 7},
+	})
+	mux := http.NewServeMux()
+	hypertext.TemplateRoutes(mux, f)
+
+	for _, path := range []string{
+		hypertext.TemplateRoutePaths{}.List(),
+		hypertext.TemplateRoutePaths{}.GetFormEditRow(1),
+	} {
+		b.Run(path, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+				if rec.Code != http.StatusOK {
+					b.Fatalf("unexpected status %d", rec.Code)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"go/token"
 	"io"
 	"path/filepath"
+	"strings"
 
 	"github.com/crhntr/muxt/internal/muxt"
 )
@@ -36,6 +37,42 @@ This function also receives an argument with a type matching the name given by r
 	templateRoutePathsType     = "template-route-paths-type"
 	templateRoutePathsTypeHelp = `The type name for the type with path constructor helper methods.`
 
+	pathPrefix     = "path-prefix"
+	pathPrefixHelp = `A path (like "/admin") prepended to each route pattern and to the paths returned by the template-route-paths-type methods. The part before the first "/" (like "example.com/admin") is used as the host for routes without one. The prefix is fixed when the routes are generated.`
+
+	muxInterfaceType     = "mux-interface-type"
+	muxInterfaceTypeHelp = `The type name for an interface used as the routes-func mux parameter instead of *"net/http".ServeMux. The interface has a Handle method so handlers can be registered on other routers.`
+
+	routeMetadataType     = "route-metadata-type"
+	routeMetadataTypeHelp = `The type name for route metadata. When set, a variable named for routes-func with a "Metadata" suffix lists the method, host, path, pattern, status code, receiver method, template name, and path value names and types of each route.`
+
+	multipartMaxMemory     = "multipart-max-memory"
+	multipartMaxMemoryHelp = `The maxMemory argument in bytes passed to (*"net/http".Request).ParseMultipartForm when a form struct has file fields.`
+
+	bodyMaxBytes     = "body-max-bytes"
+	bodyMaxBytesHelp = `The maximum size in bytes of a JSON request body decoded for a body argument.`
+
+	aggregateValidationErrors     = "aggregate-validation-errors"
+	aggregateValidationErrorsHelp = `Collect the parse and validation errors for all form, query, header, and cookie struct fields in a ValidationErrors map (available in templates with .ValidationErrors) instead of responding to the first error.`
+
+	negotiateJSON     = "negotiate-json"
+	negotiateJSONHelp = `Respond with the JSON encoded method result (or error) instead of the rendered template when the request Accept header includes application/json.`
+
+	streamResponses     = "stream-responses"
+	streamResponsesHelp = `Execute templates directly to the response (flushing as it is written) instead of rendering into a buffer first. Template errors after the status code is written are logged.`
+
+	errorTemplates     = "error-templates"
+	errorTemplatesHelp = `Render a template named for the status code (like "404"), the status code class ("4xx" or "5xx"), or "error" for error responses instead of the route template when one is defined.`
+
+	recoverPanics     = "recover-panics"
+	recoverPanicsHelp = `Recover panics in handlers, log them with the stack, and render the error response with a 500. If the receiver has a ReportPanic(context.Context, any, []byte) method, it is called with the recovered value and stack.`
+
+	logger     = "logger"
+	loggerHelp = `Add a *slog.Logger parameter to the routes function. Handlers log errors with it instead of the default slog logger.`
+
+	middleware     = "middleware"
+	middlewareHelp = `Add a middleware func(pattern, receiverMethod string, next http.Handler) http.Handler parameter to the routes function. Each route handler is wrapped with it before it is registered.`
+
 	errIdentSuffix = " value must be a well-formed Go identifier"
 )
 
@@ -64,9 +101,27 @@ func NewRoutesFileConfiguration(args []string, stderr io.Writer) (muxt.RoutesFil
 	if g.TemplateRoutePathsTypeName != "" && !token.IsIdentifier(g.TemplateRoutePathsTypeName) {
 		return muxt.RoutesFileConfiguration{}, fmt.Errorf(templateRoutePathsType + errIdentSuffix)
 	}
+	if strings.ContainsAny(g.PathPrefix, "{} \t") {
+		return muxt.RoutesFileConfiguration{}, fmt.Errorf(pathPrefix + " value must not have wildcards or spaces")
+	}
+	if g.PathPrefix != "" && !strings.Contains(g.PathPrefix, "/") {
+		return muxt.RoutesFileConfiguration{}, fmt.Errorf(pathPrefix + ` value must have a path like "/admin"`)
+	}
+	if g.MuxInterfaceTypeName != "" && !token.IsIdentifier(g.MuxInterfaceTypeName) {
+		return muxt.RoutesFileConfiguration{}, fmt.Errorf(muxInterfaceType + errIdentSuffix)
+	}
+	if g.RouteMetadataTypeName != "" && !token.IsIdentifier(g.RouteMetadataTypeName) {
+		return muxt.RoutesFileConfiguration{}, fmt.Errorf(routeMetadataType + errIdentSuffix)
+	}
 	if g.OutputFileName != "" && filepath.Ext(g.OutputFileName) != ".go" {
 		return muxt.RoutesFileConfiguration{}, fmt.Errorf("output filename must use .go extension")
 	}
+	if g.MultipartMaxMemory <= 0 {
+		return muxt.RoutesFileConfiguration{}, fmt.Errorf(multipartMaxMemory + " value must be positive")
+	}
+	if g.BodyMaxBytes <= 0 {
+		return muxt.RoutesFileConfiguration{}, fmt.Errorf(bodyMaxBytes + " value must be positive")
+	}
 	return g, nil
 }
 
@@ -80,5 +135,17 @@ func RoutesFileConfigurationFlagSet(g *muxt.RoutesFileConfiguration) *flag.FlagS
 	flagSet.StringVar(&g.ReceiverInterface, receiverInterfaceName, muxt.DefaultReceiverInterfaceName, receiverInterfaceNameHelp)
 	flagSet.StringVar(&g.TemplateDataType, templateDataType, muxt.DefaultTemplateDataTypeName, templateDataTypeHelp)
 	flagSet.StringVar(&g.TemplateRoutePathsTypeName, templateRoutePathsType, muxt.DefaultTemplateRoutePathsTypeName, templateRoutePathsTypeHelp)
+	flagSet.StringVar(&g.PathPrefix, pathPrefix, "", pathPrefixHelp)
+	flagSet.StringVar(&g.MuxInterfaceTypeName, muxInterfaceType, "", muxInterfaceTypeHelp)
+	flagSet.StringVar(&g.RouteMetadataTypeName, routeMetadataType, "", routeMetadataTypeHelp)
+	flagSet.Int64Var(&g.MultipartMaxMemory, multipartMaxMemory, muxt.DefaultMultipartMaxMemory, multipartMaxMemoryHelp)
+	flagSet.Int64Var(&g.BodyMaxBytes, bodyMaxBytes, muxt.DefaultBodyMaxBytes, bodyMaxBytesHelp)
+	flagSet.BoolVar(&g.AggregateValidationErrors, aggregateValidationErrors, false, aggregateValidationErrorsHelp)
+	flagSet.BoolVar(&g.NegotiateJSON, negotiateJSON, false, negotiateJSONHelp)
+	flagSet.BoolVar(&g.StreamResponses, streamResponses, false, streamResponsesHelp)
+	flagSet.BoolVar(&g.ErrorTemplates, errorTemplates, false, errorTemplatesHelp)
+	flagSet.BoolVar(&g.RecoverPanics, recoverPanics, false, recoverPanicsHelp)
+	flagSet.BoolVar(&g.Logger, logger, false, loggerHelp)
+	flagSet.BoolVar(&g.Middleware, middleware, false, middlewareHelp)
 	return flagSet
 }
@@ -32,10 +32,95 @@ func TestNewGenerate(t *testing.T) {
 		}, io.Discard)
 		assert.ErrorContains(t, err, errIdentSuffix)
 	})
+	t.Run(muxInterfaceType+" flag value is an invalid identifier", func(t *testing.T) {
+		_, err := NewRoutesFileConfiguration([]string{
+			"--" + muxInterfaceType, "mux.Router",
+		}, io.Discard)
+		assert.ErrorContains(t, err, errIdentSuffix)
+	})
+	t.Run(routeMetadataType+" flag value is an invalid identifier", func(t *testing.T) {
+		_, err := NewRoutesFileConfiguration([]string{
+			"--" + routeMetadataType, "123",
+		}, io.Discard)
+		assert.ErrorContains(t, err, errIdentSuffix)
+	})
+	t.Run(pathPrefix+" flag value has a wildcard", func(t *testing.T) {
+		_, err := NewRoutesFileConfiguration([]string{
+			"--" + pathPrefix, "/tenant/{id}",
+		}, io.Discard)
+		assert.ErrorContains(t, err, "must not have wildcards")
+	})
+	t.Run(pathPrefix+" flag value is only a host", func(t *testing.T) {
+		_, err := NewRoutesFileConfiguration([]string{
+			"--" + pathPrefix, "tenant.example.com",
+		}, io.Discard)
+		assert.ErrorContains(t, err, "must have a path")
+	})
 	t.Run(outputFlagName+" flag value is not a go file", func(t *testing.T) {
 		_, err := NewRoutesFileConfiguration([]string{
 			"--" + outputFlagName, "output.txt",
 		}, io.Discard)
 		assert.ErrorContains(t, err, "filename must use .go extension")
 	})
+	t.Run(multipartMaxMemory+" flag value is not positive", func(t *testing.T) {
+		_, err := NewRoutesFileConfiguration([]string{
+			"--" + multipartMaxMemory, "0",
+		}, io.Discard)
+		assert.ErrorContains(t, err, "must be positive")
+	})
+	t.Run(bodyMaxBytes+" flag value is not positive", func(t *testing.T) {
+		_, err := NewRoutesFileConfiguration([]string{
+			"--" + bodyMaxBytes, "-1",
+		}, io.Discard)
+		assert.ErrorContains(t, err, "must be positive")
+	})
+	t.Run(aggregateValidationErrors+" flag is set", func(t *testing.T) {
+		g, err := NewRoutesFileConfiguration([]string{
+			"--" + aggregateValidationErrors,
+		}, io.Discard)
+		assert.NoError(t, err)
+		assert.True(t, g.AggregateValidationErrors)
+	})
+	t.Run(negotiateJSON+" flag is set", func(t *testing.T) {
+		g, err := NewRoutesFileConfiguration([]string{
+			"--" + negotiateJSON,
+		}, io.Discard)
+		assert.NoError(t, err)
+		assert.True(t, g.NegotiateJSON)
+	})
+	t.Run(streamResponses+" flag is set", func(t *testing.T) {
+		g, err := NewRoutesFileConfiguration([]string{
+			"--" + streamResponses,
+		}, io.Discard)
+		assert.NoError(t, err)
+		assert.True(t, g.StreamResponses)
+	})
+	t.Run(errorTemplates+" flag is set", func(t *testing.T) {
+		g, err := NewRoutesFileConfiguration([]string{
+			"--" + errorTemplates,
+		}, io.Discard)
+		assert.NoError(t, err)
+		assert.True(t, g.ErrorTemplates)
+	})
+	t.Run(recoverPanics+" flag is set", func(t *testing.T) {
+		g, err := NewRoutesFileConfiguration([]string{
+			"--" + recoverPanics,
+		}, io.Discard)
+		assert.NoError(t, err)
+		assert.True(t, g.RecoverPanics)
+	})
+	t.Run(logger+" flag is set", func(t *testing.T) {
+		g, err := NewRoutesFileConfiguration([]string{
+			"--" + logger,
+		}, io.Discard)
+		assert.NoError(t, err)
+		assert.True(t, g.Logger)
+	})
+	t.Run(middleware+" flag is set", func(t *testing.T) {
+		g, err := NewRoutesFileConfiguration([]string{
+			"--" + middleware,
+		}, io.Discard)
+		assert.NoError(t, err)
+		assert.True(t, g.Middleware)
+	})
 }
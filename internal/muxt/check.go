@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
+	"go/types"
 	"log"
 	"path/filepath"
+	"strconv"
 
 	"github.com/typelate/check"
 	"golang.org/x/tools/go/packages"
@@ -58,6 +60,9 @@ func Check(wd string, log *log.Logger, config RoutesFileConfiguration) error {
 	for _, file := range routesPkg.Syntax {
 		for node := range ast.Preorder(file) {
 			templateName, dataType, ok := source.ExecuteTemplateArguments(node, routesPkg.TypesInfo, config.TemplatesVariable)
+			if !ok {
+				templateName, dataType, ok = renderArguments(node, routesPkg.TypesInfo, config.TemplateDataType)
+			}
 			if !ok {
 				continue
 			}
@@ -88,3 +93,33 @@ func Check(wd string, log *log.Logger, config RoutesFileConfiguration) error {
 	log.Println("OK")
 	return nil
 }
+
+// renderArguments returns the template name and data type for generated calls like td.render("GET /", "", http.StatusOK)
+func renderArguments(node ast.Node, info *types.Info, templateDataTypeName string) (string, types.Type, bool) {
+	call, ok := node.(*ast.CallExpr)
+	if !ok || len(call.Args) != 3 {
+		return "", nil, false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != templateDataRenderMethod {
+		return "", nil, false
+	}
+	dataType := info.TypeOf(sel.X)
+	ptr, ok := dataType.(*types.Pointer)
+	if !ok {
+		return "", nil, false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok || named.Obj().Name() != templateDataTypeName {
+		return "", nil, false
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", nil, false
+	}
+	templateName, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", nil, false
+	}
+	return templateName, dataType, true
+}
@@ -8,10 +8,12 @@ import (
 	"go/token"
 	"go/types"
 	"net/http"
+	"reflect"
 	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/crhntr/dom/spec"
 	"github.com/ettle/strcase"
 
 	"github.com/crhntr/muxt/internal/source"
@@ -33,6 +35,10 @@ func (t Template) generateEndpointPatternIdentifier(sb *strings.Builder) string
 		sb.WriteString("Update")
 	case http.MethodDelete:
 		sb.WriteString("Delete")
+	case http.MethodHead:
+		sb.WriteString("Head")
+	case http.MethodOptions:
+		sb.WriteString("Options")
 	default:
 		sb.WriteString(strcase.ToGoPascal(t.method))
 	}
@@ -140,8 +146,7 @@ func routePathFunc(imports *source.File, t *Template, urlHelperTypeName string)
 	}
 
 	if t.path == "/" || t.path == "/{$}" {
-		method.Body.List = []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: `"/"`}}}}
-		return method, nil
+		return routePathReturn(imports, t, method, &ast.BasicLit{Kind: token.STRING, Value: `"/"`}, textMarshalerInterface)
 	}
 
 	templatePath, hasDollarSuffix := strings.CutSuffix(t.path, "{$}")
@@ -156,11 +161,9 @@ func routePathFunc(imports *source.File, t *Template, urlHelperTypeName string)
 		segmentIdentifiers = t.parsePathValueNames()
 	)
 	if len(segmentIdentifiers) == 0 {
-		method.Body.List = []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(templatePath)}}}}
-		return method, nil
+		return routePathReturn(imports, t, method, &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(templatePath)}, textMarshalerInterface)
 	}
 
-	hasErrorResult := false
 	for si, segment := range segmentStrings {
 		if len(segment) < 1 {
 			continue
@@ -206,7 +209,6 @@ func routePathFunc(imports *source.File, t *Template, urlHelperTypeName string)
 		pathHash := hex.EncodeToString(summer.Sum(nil))
 
 		if types.Implements(pathValueType, textMarshalerInterface) {
-			hasErrorResult = true
 			if len(method.Type.Results.List) == 1 {
 				method.Type.Results.List = append(method.Type.Results.List, &ast.Field{
 					Type: ast.NewIdent("error"),
@@ -232,7 +234,7 @@ func routePathFunc(imports *source.File, t *Template, urlHelperTypeName string)
 						&ast.ReturnStmt{
 							Results: []ast.Expr{
 								&ast.BasicLit{Kind: token.STRING, Value: `""`},
-								imports.Call("fmt", "fmt", "Errorf", []ast.Expr{
+								imports.Call("", "fmt", "Errorf", []ast.Expr{
 									source.String(fmt.Sprintf("failed to marshal path value {%s} (segment %d) in %s: %%w", ident, si, t.path)),
 									ast.NewIdent("err"),
 								}),
@@ -248,6 +250,13 @@ func routePathFunc(imports *source.File, t *Template, urlHelperTypeName string)
 			continue
 		}
 
+		if source.IsNamed(pathValueType, "time", "Duration") {
+			segmentExpressions = append(segmentExpressions, &ast.CallExpr{
+				Fun: &ast.SelectorExpr{X: ast.NewIdent(ident), Sel: ast.NewIdent("String")},
+			})
+			continue
+		}
+
 		basicType, ok := pathValueType.Underlying().(*types.Basic)
 		if !ok {
 			return nil, fmt.Errorf("unsupported type %s for path parameters: %s", source.Format(tpNode), ident)
@@ -284,17 +293,257 @@ func routePathFunc(imports *source.File, t *Template, urlHelperTypeName string)
 		}
 	}
 
-	if hasErrorResult {
-		method.Body.List = append(method.Body.List, &ast.ReturnStmt{Results: []ast.Expr{returnStmt, source.Nil()}})
-	} else {
-		method.Body.List = append(method.Body.List, &ast.ReturnStmt{Results: []ast.Expr{returnStmt}})
+	method.Type.Params.List = fields
+
+	return routePathReturn(imports, t, method, returnStmt, textMarshalerInterface)
+}
+
+// routePathReturn appends the statements returning pathExp to the route path method.
+// When the route has a query argument, the method gets a variadic query parameter
+// and the given values are encoded in the query string.
+func routePathReturn(imports *source.File, t *Template, method *ast.FuncDecl, pathExp ast.Expr, textMarshalerInterface *types.Interface) (*ast.FuncDecl, error) {
+	const (
+		queryParamIdent  = TemplateNameScopeIdentifierQuery
+		queryValueIdent  = "q"
+		queryValuesIdent = "queryValues"
+	)
+	returnStmt := func(exp ast.Expr) *ast.ReturnStmt {
+		if len(method.Type.Results.List) > 1 {
+			return &ast.ReturnStmt{Results: []ast.Expr{exp, source.Nil()}}
+		}
+		return &ast.ReturnStmt{Results: []ast.Expr{exp}}
+	}
+	if t.queryType == nil {
+		method.Body.List = append(method.Body.List, returnStmt(pathExp))
+		return method, nil
 	}
 
-	method.Type.Params.List = fields
+	queryTypeExp, err := imports.TypeASTExpression(t.queryType)
+	if err != nil {
+		return nil, err
+	}
+	method.Type.Params.List = append(method.Type.Params.List, &ast.Field{
+		Names: []*ast.Ident{ast.NewIdent(queryParamIdent)},
+		Type:  &ast.Ellipsis{Elt: queryTypeExp},
+	})
+
+	var addValues []ast.Stmt
+	if structType, ok := t.queryType.Underlying().(*types.Struct); ok {
+		addValues, err = appendQueryValuesStatements(nil, imports, t, method, ast.NewIdent(queryValueIdent), "", structType, queryValuesIdent, textMarshalerInterface)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		addValues = []ast.Stmt{&ast.RangeStmt{
+			Key:   ast.NewIdent("key"),
+			Value: ast.NewIdent("values"),
+			Tok:   token.DEFINE,
+			X:     ast.NewIdent(queryValueIdent),
+			Body: &ast.BlockStmt{List: []ast.Stmt{&ast.AssignStmt{
+				Lhs: []ast.Expr{&ast.IndexExpr{X: ast.NewIdent(queryValuesIdent), Index: ast.NewIdent("key")}},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.CallExpr{
+					Fun:      ast.NewIdent("append"),
+					Args:     []ast.Expr{&ast.IndexExpr{X: ast.NewIdent(queryValuesIdent), Index: ast.NewIdent("key")}, ast.NewIdent("values")},
+					Ellipsis: 1,
+				}},
+			}}},
+		}}
+	}
 
+	var pathWithQuestionMark ast.Expr = &ast.BinaryExpr{X: pathExp, Op: token.ADD, Y: source.String("?")}
+	if lit, ok := pathExp.(*ast.BasicLit); ok {
+		p, _ := strconv.Unquote(lit.Value)
+		pathWithQuestionMark = source.String(p + "?")
+	}
+	method.Body.List = append(method.Body.List,
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(queryValuesIdent)},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.CallExpr{Fun: ast.NewIdent("make"), Args: []ast.Expr{&ast.SelectorExpr{X: ast.NewIdent(imports.Import("", "net/url")), Sel: ast.NewIdent("Values")}}}},
+		},
+		&ast.RangeStmt{
+			Key:   ast.NewIdent("_"),
+			Value: ast.NewIdent(queryValueIdent),
+			Tok:   token.DEFINE,
+			X:     ast.NewIdent(queryParamIdent),
+			Body:  &ast.BlockStmt{List: addValues},
+		},
+		&ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{ast.NewIdent(queryValuesIdent)}}, Op: token.EQL, Y: source.Int(0)},
+			Body: &ast.BlockStmt{List: []ast.Stmt{returnStmt(pathExp)}},
+		},
+		returnStmt(&ast.BinaryExpr{
+			X:  pathWithQuestionMark,
+			Op: token.ADD,
+			Y:  &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(queryValuesIdent), Sel: ast.NewIdent("Encode")}},
+		}),
+	)
 	return method, nil
 }
 
+// appendQueryValuesStatements adds the fields of structType on target to the url.Values variable named queryValuesIdent.
+// Field names and time formats match what the handler parses: nested struct fields use dotted names, embedded struct fields
+// are promoted, and time.Time fields with a date or time input use the input format.
+// Zero values and nil pointers are not added unless the input is required. Fields with types that can not be formatted are skipped.
+func appendQueryValuesStatements(statements []ast.Stmt, imports *source.File, t *Template, method *ast.FuncDecl, target ast.Expr, namePrefix string, structType *types.Struct, queryValuesIdent string, textMarshalerInterface *types.Interface) ([]ast.Stmt, error) {
+	for i := 0; i < structType.NumFields(); i++ {
+		field, tags := structType.Field(i), reflect.StructTag(structType.Tag(i))
+		if outPkg := imports.OutputPackage(); !field.Exported() && outPkg != nil && field.Pkg() != nil && field.Pkg().Path() != outPkg.PkgPath {
+			continue
+		}
+		fieldName, hasNameTag := tags.Lookup(InputAttributeNameStructTag)
+		if !hasNameTag {
+			fieldName = field.Name()
+		}
+		name := namePrefix + fieldName
+		fieldExpr := &ast.SelectorExpr{X: target, Sel: ast.NewIdent(field.Name())}
+
+		if nested, ok := nestedStructType(imports, field.Type()); ok {
+			prefix := namePrefix
+			if !field.Embedded() || hasNameTag {
+				prefix = name + "."
+			}
+			var err error
+			statements, err = appendQueryValuesStatements(statements, imports, t, method, fieldExpr, prefix, nested, queryValuesIdent, textMarshalerInterface)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		input := structFieldTemplateFragment(t, tags).QuerySelector(fmt.Sprintf("[name=%q]", name))
+		switch tp := field.Type().(type) {
+		case *types.Slice:
+			const elemIdent = "value"
+			add, ok := queryValueAddStatements(imports, method, name, ast.NewIdent(elemIdent), tp.Elem(), input, queryValuesIdent, textMarshalerInterface)
+			if !ok {
+				continue
+			}
+			statements = append(statements, &ast.RangeStmt{
+				Key:   ast.NewIdent("_"),
+				Value: ast.NewIdent(elemIdent),
+				Tok:   token.DEFINE,
+				X:     fieldExpr,
+				Body:  &ast.BlockStmt{List: add},
+			})
+		case *types.Pointer:
+			add, ok := queryValueAddStatements(imports, method, name, &ast.StarExpr{X: fieldExpr}, tp.Elem(), input, queryValuesIdent, textMarshalerInterface)
+			if !ok {
+				continue
+			}
+			statements = append(statements, &ast.IfStmt{
+				Cond: &ast.BinaryExpr{X: fieldExpr, Op: token.NEQ, Y: source.Nil()},
+				Body: &ast.BlockStmt{List: add},
+			})
+		default:
+			add, ok := queryValueAddStatements(imports, method, name, fieldExpr, tp, input, queryValuesIdent, textMarshalerInterface)
+			if !ok {
+				continue
+			}
+			// a checkbox is unchecked when its value is left out, so it is left out even when the input is required
+			required := input != nil && input.HasAttribute("required") && !strings.EqualFold(input.GetAttribute("type"), "checkbox")
+			if isNotZero, ok := queryValueIsNotZero(fieldExpr, tp, textMarshalerInterface); ok && !required {
+				statements = append(statements, &ast.IfStmt{Cond: isNotZero, Body: &ast.BlockStmt{List: add}})
+			} else if len(add) > 1 {
+				statements = append(statements, &ast.BlockStmt{List: add})
+			} else {
+				statements = append(statements, add...)
+			}
+		}
+	}
+	return statements, nil
+}
+
+// queryValueAddStatements formats value the way the handler parses it and adds it to the url.Values variable named queryValuesIdent.
+// When an encoding.TextMarshaler value fails to marshal, the error is returned if the method has an error result;
+// otherwise the value is left out so the method signature does not change.
+func queryValueAddStatements(imports *source.File, method *ast.FuncDecl, name string, value ast.Expr, tp types.Type, input spec.Element, queryValuesIdent string, textMarshalerInterface *types.Interface) ([]ast.Stmt, bool) {
+	add := func(exp ast.Expr) *ast.ExprStmt {
+		return &ast.ExprStmt{X: &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: ast.NewIdent(queryValuesIdent), Sel: ast.NewIdent("Add")},
+			Args: []ast.Expr{source.String(name), exp},
+		}}
+	}
+	if input != nil && source.IsNamed(tp, "time", "Time") {
+		if input.GetAttribute("type") == "week" {
+			const (
+				yearIdent = "year"
+				weekIdent = "week"
+			)
+			return []ast.Stmt{
+				&ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent(yearIdent), ast.NewIdent(weekIdent)},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{&ast.CallExpr{Fun: &ast.SelectorExpr{X: value, Sel: ast.NewIdent("ISOWeek")}}},
+				},
+				add(imports.Call("", "fmt", "Sprintf", []ast.Expr{source.String("%04d-W%02d"), ast.NewIdent(yearIdent), ast.NewIdent(weekIdent)})),
+			}, true
+		}
+		if layout, ok := source.TimeInputLayout(input); ok {
+			return []ast.Stmt{add(&ast.CallExpr{Fun: &ast.SelectorExpr{X: value, Sel: ast.NewIdent("Format")}, Args: []ast.Expr{source.String(layout)}})}, true
+		}
+	}
+	switch {
+	case source.IsNamed(tp, "time", "Duration"):
+		return []ast.Stmt{add(&ast.CallExpr{Fun: &ast.SelectorExpr{X: value, Sel: ast.NewIdent("String")}})}, true
+	case types.Implements(tp, textMarshalerInterface) || types.Implements(types.NewPointer(tp), textMarshalerInterface):
+		const textIdent = "text"
+		marshal := &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(textIdent), ast.NewIdent(errIdent)},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.CallExpr{Fun: &ast.SelectorExpr{X: value, Sel: ast.NewIdent("MarshalText")}}},
+		}
+		addText := add(&ast.CallExpr{Fun: ast.NewIdent("string"), Args: []ast.Expr{ast.NewIdent(textIdent)}})
+		if len(method.Type.Results.List) == 1 {
+			return []ast.Stmt{marshal, &ast.IfStmt{
+				Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.EQL, Y: source.Nil()},
+				Body: &ast.BlockStmt{List: []ast.Stmt{addText}},
+			}}, true
+		}
+		return []ast.Stmt{marshal, &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: source.Nil()},
+			Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{
+				source.String(""),
+				imports.Call("", "fmt", "Errorf", []ast.Expr{source.String(fmt.Sprintf("failed to marshal query value %s: %%w", name)), ast.NewIdent(errIdent)}),
+			}}}},
+		}, addText}, true
+	}
+	basicType, ok := tp.Underlying().(*types.Basic)
+	if !ok {
+		return nil, false
+	}
+	exp, err := imports.Format(value, basicType.Kind())
+	if err != nil {
+		return nil, false
+	}
+	return []ast.Stmt{add(exp)}, true
+}
+
+// queryValueIsNotZero returns an expression checking value is not the zero value.
+// It is not ok for text marshalers without an IsZero method; those values are always added.
+func queryValueIsNotZero(value ast.Expr, tp types.Type, textMarshalerInterface *types.Interface) (ast.Expr, bool) {
+	if types.Implements(tp, textMarshalerInterface) || types.Implements(types.NewPointer(tp), textMarshalerInterface) {
+		obj, _, _ := types.LookupFieldOrMethod(tp, true, nil, "IsZero")
+		if fn, ok := obj.(*types.Func); !ok || fn.Type().(*types.Signature).Params().Len() != 0 {
+			return nil, false
+		}
+		return &ast.UnaryExpr{Op: token.NOT, X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: value, Sel: ast.NewIdent("IsZero")}}}, true
+	}
+	basicType, ok := tp.Underlying().(*types.Basic)
+	if !ok {
+		return nil, false
+	}
+	switch info := basicType.Info(); {
+	case info&types.IsBoolean != 0:
+		return value, true
+	case info&types.IsString != 0:
+		return &ast.BinaryExpr{X: value, Op: token.NEQ, Y: source.String("")}, true
+	default:
+		return &ast.BinaryExpr{X: value, Op: token.NEQ, Y: source.Int(0)}, true
+	}
+}
+
 func routePathTypeAndMethods(imports *source.File, templates []Template, urlHelperTypeName string) ([]ast.Decl, error) {
 	decls := []ast.Decl{
 		&ast.GenDecl{
@@ -313,3 +562,81 @@ func routePathTypeAndMethods(imports *source.File, templates []Template, urlHelp
 	}
 	return decls, nil
 }
+
+const routeMetadataVariableSuffix = "Metadata"
+
+// routeMetadataTypeAndVariable declares the route metadata type and a variable with the metadata for each template.
+// It must be called after the handlers are generated so the path value types are resolved.
+func routeMetadataTypeAndVariable(templates []Template, outputPkg *types.Package, typeName, variableName string) []ast.Decl {
+	field := func(name string, tp ast.Expr) *ast.Field {
+		return &ast.Field{Names: []*ast.Ident{ast.NewIdent(name)}, Type: tp}
+	}
+	stringSlice := func() ast.Expr { return &ast.ArrayType{Elt: ast.NewIdent("string")} }
+	typeDecl := &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{&ast.TypeSpec{
+			Name: ast.NewIdent(typeName),
+			Type: &ast.StructType{Fields: &ast.FieldList{List: []*ast.Field{
+				field("Method", ast.NewIdent("string")),
+				field("Host", ast.NewIdent("string")),
+				field("Path", ast.NewIdent("string")),
+				field("Pattern", ast.NewIdent("string")),
+				field("StatusCode", ast.NewIdent("int")),
+				field("ReceiverMethod", ast.NewIdent("string")),
+				field("TemplateName", ast.NewIdent("string")),
+				field("PathValueNames", stringSlice()),
+				field("PathValueTypes", stringSlice()),
+			}}},
+		}},
+	}
+
+	qualifier := func(pkg *types.Package) string {
+		if pkg == outputPkg {
+			return ""
+		}
+		return pkg.Name()
+	}
+	routes := make([]ast.Expr, 0, len(templates))
+	for _, t := range templates {
+		var elts []ast.Expr
+		stringField := func(name, value string) {
+			if value != "" {
+				elts = append(elts, &ast.KeyValueExpr{Key: ast.NewIdent(name), Value: source.String(value)})
+			}
+		}
+		stringField("Method", t.method)
+		stringField("Host", t.host)
+		stringField("Path", t.path)
+		stringField("Pattern", t.pattern)
+		elts = append(elts, &ast.KeyValueExpr{Key: ast.NewIdent("StatusCode"), Value: source.Int(t.defaultStatusCode)})
+		if t.fun != nil {
+			stringField("ReceiverMethod", t.fun.Name)
+		}
+		stringField("TemplateName", t.name)
+		if len(t.pathValueNames) > 0 {
+			names := make([]ast.Expr, 0, len(t.pathValueNames))
+			pathValueTypes := make([]ast.Expr, 0, len(t.pathValueNames))
+			for _, name := range t.pathValueNames {
+				names = append(names, source.String(name))
+				tp, ok := t.pathValueTypes[name]
+				if !ok {
+					tp = types.Universe.Lookup("string").Type()
+				}
+				pathValueTypes = append(pathValueTypes, source.String(types.TypeString(tp, qualifier)))
+			}
+			elts = append(elts,
+				&ast.KeyValueExpr{Key: ast.NewIdent("PathValueNames"), Value: &ast.CompositeLit{Type: stringSlice(), Elts: names}},
+				&ast.KeyValueExpr{Key: ast.NewIdent("PathValueTypes"), Value: &ast.CompositeLit{Type: stringSlice(), Elts: pathValueTypes}},
+			)
+		}
+		routes = append(routes, &ast.CompositeLit{Elts: elts})
+	}
+	varDecl := &ast.GenDecl{
+		Tok: token.VAR,
+		Specs: []ast.Spec{&ast.ValueSpec{
+			Names:  []*ast.Ident{ast.NewIdent(variableName)},
+			Values: []ast.Expr{&ast.CompositeLit{Type: &ast.ArrayType{Elt: ast.NewIdent(typeName)}, Elts: routes}},
+		}},
+	}
+	return []ast.Decl{typeDecl, varDecl}
+}
@@ -35,6 +35,18 @@ func TestTemplate_generateEndpointPatternIdentifier(t *testing.T) {
 			Out: "DeleteArticle",
 			In:  "DELETE /article",
 		},
+		{
+			Out: "HeadArticle",
+			In:  "HEAD /article",
+		},
+		{
+			Out: "OptionsArticle",
+			In:  "OPTIONS /article",
+		},
+		{
+			Out: "HeadReportByID",
+			In:  "HEAD /report/{id} F(id)",
+		},
 		{
 			Out: "Article",
 			In:  "/article",
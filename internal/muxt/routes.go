@@ -15,8 +15,11 @@ import (
 	"strconv"
 	"strings"
 	"text/template/parse"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/crhntr/dom"
+	"github.com/crhntr/dom/spec"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
@@ -35,6 +38,7 @@ const (
 	httpResponseWriterIdent  = "ResponseWriter"
 	httpRequestIdent         = "Request"
 	httpHandleFuncIdent      = "HandleFunc"
+	httpHandleIdent          = "Handle"
 
 	defaultPackageName                = "main"
 	DefaultTemplatesVariableName      = "templates"
@@ -42,25 +46,74 @@ const (
 	DefaultOutputFileName             = "template_routes.go"
 	DefaultReceiverInterfaceName      = "RoutesReceiver"
 	DefaultTemplateRoutePathsTypeName = "TemplateRoutePaths"
+	DefaultMultipartMaxMemory         = 32 << 20
+	DefaultBodyMaxBytes               = 1 << 20
 
 	InputAttributeNameStructTag     = "name"
 	InputAttributeTemplateStructTag = "template"
+	HeaderNameStructTag             = "header"
+	CookieNameStructTag             = "cookie"
 
-	muxParamName      = "mux"
-	receiverParamName = "receiver"
+	muxParamName        = "mux"
+	receiverParamName   = "receiver"
+	loggerParamName     = "logger"
+	middlewareParamName = "middleware"
 
 	errIdent = "err"
 
 	DefaultTemplateDataTypeName = "TemplateData"
 	templateDataFieldStatusCode = "statusCode"
+	templateDataFieldLogger     = "logger"
+
+	templateDataValidationErrorsMethod = "ValidationErrors"
+
+	templateDataAcceptsJSONMethod = "acceptsJSON"
+	templateDataWriteJSONMethod   = "writeJSON"
+	jsonContentType               = "application/json"
+
+	templateDataRenderMethod          = "render"
+	templateDataErrorStatusCodeMethod = "errorStatusCode"
+	templateDataErrorTemplateMethod   = "errorTemplateName"
+
+	maxPooledBufferSize = 64 << 10
 
 	executeTemplateErrorMessage = "failed to render page"
+	handlerPanicMessage         = "handler panic"
+	handlerPanicErrorMessage    = "internal server error"
+
+	panicReporterMethod = "ReportPanic"
 )
 
 func newResponseDataFuncIdent(templateDataTypeName string) string {
 	return "new" + templateDataTypeName
 }
 
+func bufferPoolVarIdent(templateDataTypeName string) string {
+	r, size := utf8.DecodeRuneInString(templateDataTypeName)
+	return string(unicode.ToLower(r)) + templateDataTypeName[size:] + "BufferPool"
+}
+
+func putBufferFuncIdent(templateDataTypeName string) string {
+	return "put" + templateDataTypeName + "Buffer"
+}
+
+func handlePanicFuncIdent(templateDataTypeName string) string {
+	return "handle" + templateDataTypeName + "Panic"
+}
+
+func validationErrorsTypeIdent(templateDataTypeName string) string {
+	return templateDataTypeName + "ValidationErrors"
+}
+
+func flushWriterTypeIdent(templateDataTypeName string) string {
+	r, size := utf8.DecodeRuneInString(templateDataTypeName)
+	return string(unicode.ToLower(r)) + templateDataTypeName[size:] + "FlushWriter"
+}
+
+func parseWeekFuncIdent(templateDataTypeName string) string {
+	return "parse" + templateDataTypeName + "Week"
+}
+
 type RoutesFileConfiguration struct {
 	MuxtVersion,
 	PackageName,
@@ -72,7 +125,39 @@ type RoutesFileConfiguration struct {
 	ReceiverInterface,
 	TemplateDataType,
 	TemplateRoutePathsTypeName string
-	OutputFileName string
+	// PathPrefix is prepended to the path of each route pattern and the paths returned by the TemplateRoutePaths methods.
+	// It must have a path. When it has a host (like "example.com/admin"), the host is used for routes without one.
+	PathPrefix string
+	// MuxInterfaceTypeName is the type name for an interface used as the routes function mux parameter
+	// instead of *http.ServeMux. The interface has the Handle method the generated code calls.
+	MuxInterfaceTypeName string
+	// RouteMetadataTypeName is the type name for the route metadata. When it is set, a slice describing
+	// each route is declared in a variable named for the routes function with a "Metadata" suffix.
+	RouteMetadataTypeName string
+	OutputFileName        string
+	MultipartMaxMemory    int64
+	BodyMaxBytes          int64
+	// AggregateValidationErrors makes handlers collect the parse and validation errors for every struct field
+	// in a ValidationErrors value instead of responding with the first error.
+	AggregateValidationErrors bool
+	// NegotiateJSON makes handlers respond with the JSON encoded result (or error)
+	// instead of executing the template when the request accepts application/json.
+	NegotiateJSON bool
+	// StreamResponses makes handlers write the status and headers before executing the template
+	// directly to the response. Template errors after the first byte is written are logged.
+	StreamResponses bool
+	// ErrorTemplates makes error responses render a template named for the status code (like "404"),
+	// the status code class ("4xx" or "5xx"), or "error" instead of the route template when one is defined.
+	ErrorTemplates bool
+	// RecoverPanics makes handlers recover panics, log them with the stack, and render the error response with a 500.
+	// When the receiver has a ReportPanic method, it is called with the recovered value and the stack.
+	RecoverPanics bool
+	// Logger adds a *slog.Logger parameter to the routes function. Handlers log errors with it
+	// instead of the default logger.
+	Logger bool
+	// Middleware adds a middleware function parameter to the routes function. Each handler is wrapped with it
+	// before it is registered. The middleware is called with the route pattern and receiver method name.
+	Middleware bool
 }
 
 func (config RoutesFileConfiguration) applyDefaults() RoutesFileConfiguration {
@@ -82,6 +167,8 @@ func (config RoutesFileConfiguration) applyDefaults() RoutesFileConfiguration {
 	config.ReceiverInterface = cmp.Or(config.ReceiverInterface, DefaultReceiverInterfaceName)
 	config.TemplateDataType = cmp.Or(config.TemplateDataType, DefaultTemplateDataTypeName)
 	config.TemplateRoutePathsTypeName = cmp.Or(config.TemplateRoutePathsTypeName, DefaultTemplateRoutePathsTypeName)
+	config.MultipartMaxMemory = cmp.Or(config.MultipartMaxMemory, DefaultMultipartMaxMemory)
+	config.BodyMaxBytes = cmp.Or(config.BodyMaxBytes, DefaultBodyMaxBytes)
 	return config
 }
 
@@ -92,7 +179,7 @@ func TemplateRoutesFile(wd string, logger *log.Logger, config RoutesFileConfigur
 	}
 
 	patterns := []string{
-		wd, "encoding", "fmt", "net/http",
+		wd, "encoding", "encoding/json", "fmt", "net/http",
 	}
 
 	if config.ReceiverPackage != "" {
@@ -130,6 +217,13 @@ func TemplateRoutesFile(wd string, logger *log.Logger, config RoutesFileConfigur
 	if err != nil {
 		return "", err
 	}
+	if config.PathPrefix != "" {
+		for i := range templates {
+			if err := templates[i].addPathPrefix(config.PathPrefix); err != nil {
+				return "", err
+			}
+		}
+	}
 
 	receiverInterface := &ast.InterfaceType{
 		Methods: new(ast.FieldList),
@@ -140,7 +234,7 @@ func TemplateRoutesFile(wd string, logger *log.Logger, config RoutesFileConfigur
 		Type: &ast.FuncType{
 			Params: &ast.FieldList{
 				List: []*ast.Field{
-					httpServeMuxField(file),
+					httpServeMuxField(file, config.MuxInterfaceTypeName),
 					{
 						Names: []*ast.Ident{ast.NewIdent(receiverParamName)},
 						Type:  ast.NewIdent(config.ReceiverInterface),
@@ -150,22 +244,51 @@ func TemplateRoutesFile(wd string, logger *log.Logger, config RoutesFileConfigur
 		},
 		Body: &ast.BlockStmt{List: []ast.Stmt{}},
 	}
+	if config.Logger {
+		routesFunc.Type.Params.List = append(routesFunc.Type.Params.List, slogLoggerField(file))
+		routesFunc.Body.List = append(routesFunc.Body.List, &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(loggerParamName), Op: token.EQL, Y: source.Nil()},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				singleAssignment(token.ASSIGN, ast.NewIdent(loggerParamName))(file.Call("", "log/slog", "Default", nil)),
+			}},
+		})
+	}
+
+	if config.Middleware {
+		routesFunc.Type.Params.List = append(routesFunc.Type.Params.List, middlewareField(file))
+		routesFunc.Body.List = append(routesFunc.Body.List, &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(middlewareParamName), Op: token.EQL, Y: source.Nil()},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				singleAssignment(token.ASSIGN, ast.NewIdent(middlewareParamName))(&ast.FuncLit{
+					Type: middlewareFuncType(file, true),
+					Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent(middlewareNextParamName)}}}},
+				}),
+			}},
+		})
+	}
 
 	for i := range templates {
 		t := &templates[i]
 		const dataVarIdent = "result"
 		logger.Printf("generating handler for pattern %s", t.pattern)
+		t.negotiateJSON = config.NegotiateJSON
+		t.streamResponse = config.StreamResponses
+		t.errorTemplates = config.ErrorTemplates
+		t.recoverPanics = config.RecoverPanics
+		t.loggerParam = config.Logger
+		t.middleware = config.Middleware
+		t.muxInterface = config.MuxInterfaceTypeName != ""
 		if t.fun == nil {
-			handlerFunc := noReceiverMethodCall(file, t, config.TemplateDataType, config.TemplatesVariable, dataVarIdent)
-			call := t.callHandleFunc(handlerFunc)
+			handlerFunc := noReceiverMethodCall(file, t, config.TemplateDataType, dataVarIdent)
+			call := t.callHandleFunc(file, handlerFunc)
 			routesFunc.Body.List = append(routesFunc.Body.List, call)
 			continue
 		}
-		handlerFunc, err := methodHandlerFunc(file, t, receiver, receiverInterface, routesPkg.Types, config.TemplateDataType, config.TemplatesVariable, dataVarIdent)
+		handlerFunc, err := methodHandlerFunc(file, t, receiver, receiverInterface, routesPkg.Types, config.TemplateDataType, config.TemplatesVariable, dataVarIdent, config)
 		if err != nil {
 			return "", err
 		}
-		call := t.callHandleFunc(handlerFunc)
+		call := t.callHandleFunc(file, handlerFunc)
 		routesFunc.Body.List = append(routesFunc.Body.List, call)
 	}
 
@@ -173,6 +296,49 @@ func TemplateRoutesFile(wd string, logger *log.Logger, config RoutesFileConfigur
 	if err != nil {
 		return "", err
 	}
+	var routeMetadataDecls []ast.Decl
+	if config.RouteMetadataTypeName != "" {
+		routeMetadataDecls = routeMetadataTypeAndVariable(templates, routesPkg.Types, config.RouteMetadataTypeName, config.RoutesFunction+routeMetadataVariableSuffix)
+	}
+	var validationErrorsTypeDecls []ast.Decl
+	if config.AggregateValidationErrors {
+		validationErrorsTypeDecls = validationErrorsDecls(file, config.TemplateDataType)
+	}
+	bufferPoolTypeDecls := bufferPoolDecls(file, config.TemplateDataType)
+	var flushWriterDecls []ast.Decl
+	if config.StreamResponses {
+		flushWriterDecls = flushWriterTypeDecls(file, config.TemplateDataType)
+	}
+	var errorTemplatesDecls []ast.Decl
+	if config.ErrorTemplates {
+		errorTemplatesDecls = []ast.Decl{templateDataErrorTemplateName(file, config.TemplateDataType, config.TemplatesVariable)}
+	}
+	var recoverPanicsDecls []ast.Decl
+	if config.RecoverPanics {
+		recoverPanicsDecls = []ast.Decl{handlePanicFunc(file, config.ReceiverInterface, config.TemplateDataType, config.Logger)}
+	}
+	var parseWeekDecls []ast.Decl
+	if hasCall(routesFunc, parseWeekFuncIdent(config.TemplateDataType)) {
+		decl, err := source.ParseWeekInputValueFunc(file, parseWeekFuncIdent(config.TemplateDataType))
+		if err != nil {
+			return "", err
+		}
+		parseWeekDecls = []ast.Decl{decl}
+	}
+	var negotiateJSONDecls []ast.Decl
+	if config.NegotiateJSON {
+		negotiateJSONDecls = []ast.Decl{
+			templateDataAcceptsJSON(file, config.TemplateDataType),
+			templateDataWriteJSON(file, config.TemplateDataType, config.Logger),
+		}
+	}
+
+	typeSpecs := []ast.Spec{
+		&ast.TypeSpec{Name: ast.NewIdent(config.ReceiverInterface), Type: receiverInterface},
+	}
+	if config.MuxInterfaceTypeName != "" {
+		typeSpecs = append(typeSpecs, &ast.TypeSpec{Name: ast.NewIdent(config.MuxInterfaceTypeName), Type: muxInterfaceType(file)})
+	}
 
 	is := file.ImportSpecs()
 	importSpecs := make([]ast.Spec, 0, len(is))
@@ -190,17 +356,15 @@ func TemplateRoutesFile(wd string, logger *log.Logger, config RoutesFileConfigur
 
 			// type
 			&ast.GenDecl{
-				Tok: token.TYPE,
-				Specs: []ast.Spec{
-					&ast.TypeSpec{Name: ast.NewIdent(config.ReceiverInterface), Type: receiverInterface},
-				},
+				Tok:   token.TYPE,
+				Specs: typeSpecs,
 			},
 
 			// func routes
 			routesFunc,
 
-			templateDataType(file, config.TemplateDataType, ast.NewIdent(config.ReceiverInterface)),
-			newTemplateData(file, ast.NewIdent(config.ReceiverInterface), config.TemplateDataType),
+			templateDataType(file, config.TemplateDataType, ast.NewIdent(config.ReceiverInterface), config.Logger),
+			newTemplateData(file, ast.NewIdent(config.ReceiverInterface), config.TemplateDataType, config.Logger),
 			templateDataMuxtVersionMethod(config),
 			templateDataPathMethod(config.TemplateDataType, config.TemplateRoutePathsTypeName),
 			templateDataResultMethod(config.TemplateDataType),
@@ -211,9 +375,11 @@ func TemplateRoutesFile(wd string, logger *log.Logger, config RoutesFileConfigur
 			templateDataError(config.TemplateDataType),
 			templateDataReceiver(ast.NewIdent(config.ReceiverInterface), config.TemplateDataType),
 			templateRedirect(file, config.TemplateDataType),
+			templateDataErrorStatusCode(file, config.TemplateDataType),
+			templateDataRender(file, config.TemplateDataType, config.TemplatesVariable, config.Logger, config.ErrorTemplates),
 
 			// func newResultData
-		}, routePathDecls...),
+		}, slices.Concat(bufferPoolTypeDecls, errorTemplatesDecls, recoverPanicsDecls, negotiateJSONDecls, flushWriterDecls, validationErrorsTypeDecls, parseWeekDecls, routePathDecls, routeMetadataDecls)...),
 	}
 
 	return source.FormatFile(filepath.Join(wd, config.OutputFileName), outputFile)
@@ -242,13 +408,9 @@ func resolveReceiver(config RoutesFileConfiguration, file *source.File, routesPk
 	return named, nil
 }
 
-func noReceiverMethodCall(file *source.File, t *Template, templateDataTypeIdent, templatesVariableIdent, dataVarIdent string) *ast.FuncLit {
-	const (
-		bufIdent             = "buf"
-		statusCodeIdent      = "statusCode"
-		templateDataVarIdent = "td"
-	)
-	handlerFunc := &ast.FuncLit{
+func noReceiverMethodCall(file *source.File, t *Template, templateDataTypeIdent, dataVarIdent string) *ast.FuncLit {
+	const templateDataVarIdent = "td"
+	return &ast.FuncLit{
 		Type: httpHandlerFuncType(file),
 		Body: &ast.BlockStmt{
 			List: []ast.Stmt{
@@ -258,50 +420,17 @@ func noReceiverMethodCall(file *source.File, t *Template, templateDataTypeIdent,
 					Rhs: []ast.Expr{&ast.CompositeLit{Type: source.EmptyStructType()}},
 				},
 				&ast.AssignStmt{
-					Lhs: []ast.Expr{ast.NewIdent(bufIdent)},
+					Lhs: []ast.Expr{ast.NewIdent(templateDataVarIdent)},
 					Tok: token.DEFINE,
-					Rhs: []ast.Expr{file.BytesNewBuffer(source.Nil())},
+					Rhs: []ast.Expr{newTemplateDataCall(t, templateDataTypeIdent, ast.NewIdent(dataVarIdent), source.Bool(true), source.Nil())},
 				},
+				callRender(t, templateDataVarIdent, source.HTTPStatusCode(file, t.defaultStatusCode)),
 			},
 		},
 	}
-
-	callNewTemplateData := &ast.CallExpr{
-		Fun: ast.NewIdent(newResponseDataFuncIdent(templateDataTypeIdent)),
-		Args: []ast.Expr{
-			ast.NewIdent(receiverIdent),
-			ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse),
-			ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest),
-			ast.NewIdent(dataVarIdent),
-			ast.NewIdent("true"),
-			ast.NewIdent("nil"),
-		},
-	}
-	handlerFunc.Body.List = append(handlerFunc.Body.List, &ast.AssignStmt{
-		Lhs: []ast.Expr{ast.NewIdent(templateDataVarIdent)},
-		Tok: token.DEFINE,
-		Rhs: []ast.Expr{callNewTemplateData},
-	})
-
-	execTemplates := checkExecuteTemplateError(file)
-	execTemplates.Init = &ast.AssignStmt{
-		Lhs: []ast.Expr{
-			ast.NewIdent(errIdent),
-		},
-		Tok: token.DEFINE,
-		Rhs: []ast.Expr{&ast.CallExpr{
-			Fun:  &ast.SelectorExpr{X: ast.NewIdent(templatesVariableIdent), Sel: ast.NewIdent("ExecuteTemplate")},
-			Args: []ast.Expr{ast.NewIdent(bufIdent), &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(t.name)}, ast.NewIdent(templateDataVarIdent)},
-		}},
-	}
-
-	handlerFunc.Body.List = append(handlerFunc.Body.List, execTemplates)
-
-	handlerFunc.Body.List = append(handlerFunc.Body.List, writeStatusAndHeaders(file, t, types.NewStruct(nil, nil), t.defaultStatusCode, statusCodeIdent, bufIdent, templateDataVarIdent)...)
-	return handlerFunc
 }
 
-func methodHandlerFunc(file *source.File, t *Template, receiver *types.Named, receiverInterface *ast.InterfaceType, outputPkg *types.Package, templateDataTypeIdent, templatesVariableIdent, dataVarIdent string) (*ast.FuncLit, error) {
+func methodHandlerFunc(file *source.File, t *Template, receiver *types.Named, receiverInterface *ast.InterfaceType, outputPkg *types.Package, templateDataTypeIdent, templatesVariableIdent, dataVarIdent string, config RoutesFileConfiguration) (*ast.FuncLit, error) {
 	const (
 		bufIdent        = "buf"
 		statusCodeIdent = "statusCode"
@@ -339,8 +468,16 @@ func methodHandlerFunc(file *source.File, t *Template, receiver *types.Named, re
 
 	resultType := sig.Results().At(0).Type()
 
+	if t.recoverPanics {
+		deferRecover, err := deferRecoverPanicStatement(file, t, resultType, templateDataTypeIdent)
+		if err != nil {
+			return nil, err
+		}
+		handlerFunc.Body.List = append(handlerFunc.Body.List, deferRecover)
+	}
+
 	var err error
-	if handlerFunc.Body.List, err = appendParseArgumentStatements(handlerFunc.Body.List, t, file, resultType, sigs, nil, receiver, templateDataTypeIdent, templatesVariableIdent, t.call, func(s string) *ast.BlockStmt {
+	if handlerFunc.Body.List, err = appendParseArgumentStatements(handlerFunc.Body.List, t, file, resultType, sigs, nil, receiver, templateDataTypeIdent, templatesVariableIdent, config, t.call, func(s string) *ast.BlockStmt {
 		errBlock, err := errorResultBlock(file, t, resultType, http.StatusBadRequest, templateDataTypeIdent, templatesVariableIdent, &ast.CallExpr{
 			Fun:  &ast.SelectorExpr{X: ast.NewIdent(file.Import("", "errors")), Sel: ast.NewIdent("New")},
 			Args: []ast.Expr{source.String(s)},
@@ -365,26 +502,26 @@ func methodHandlerFunc(file *source.File, t *Template, receiver *types.Named, re
 	handlerFunc.Body.List = append(handlerFunc.Body.List, &ast.AssignStmt{
 		Lhs: []ast.Expr{ast.NewIdent(resultDataIdent)},
 		Tok: token.DEFINE,
-		Rhs: []ast.Expr{&ast.CallExpr{
-			Fun: ast.NewIdent(newResponseDataFuncIdent(templateDataTypeIdent)),
-			Args: []ast.Expr{
-				ast.NewIdent(receiverIdent),
-				ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse),
-				ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest),
-				ast.NewIdent(dataVarIdent),
-				ast.NewIdent("true"),
-				ast.NewIdent("nil"),
-			},
-		}},
+		Rhs: []ast.Expr{newTemplateDataCall(t, templateDataTypeIdent, ast.NewIdent(dataVarIdent), source.Bool(true), source.Nil())},
 	})
 
-	handlerFunc.Body.List = append(handlerFunc.Body.List, &ast.AssignStmt{
-		Lhs: []ast.Expr{ast.NewIdent(bufIdent)},
-		Tok: token.DEFINE,
-		Rhs: []ast.Expr{file.BytesNewBuffer(source.Nil())},
-	})
+	if !t.hasResponseWriterArg {
+		handlerFunc.Body.List = append(handlerFunc.Body.List, negotiateJSONStatements(file, t, resultType, t.defaultStatusCode, resultDataIdent)...)
+	}
+
+	if t.streamResponse && !t.hasResponseWriterArg && t.writesBody() {
+		handlerFunc.Body.List = append(handlerFunc.Body.List, streamTemplateStatements(file, t, resultType, templateDataTypeIdent, templatesVariableIdent, resultDataIdent)...)
+		return handlerFunc, nil
+	}
+
+	if !t.hasResponseWriterArg {
+		handlerFunc.Body.List = append(handlerFunc.Body.List, callRender(t, resultDataIdent, resultStatusCodeExpression(file, resultType, t.defaultStatusCode)))
+		return handlerFunc, nil
+	}
 
-	execTemplates := checkExecuteTemplateError(file)
+	handlerFunc.Body.List = append(handlerFunc.Body.List, pooledBufferStatements(file, templateDataTypeIdent, bufIdent)...)
+
+	execTemplates := checkExecuteTemplateError(file, routesLogger(t), handlerLogAttributes(file, t, statusCodeExpression(file, resultType, t.defaultStatusCode, resultDataIdent))...)
 	execTemplates.Init = &ast.AssignStmt{
 		Lhs: []ast.Expr{
 			ast.NewIdent(errIdent),
@@ -398,24 +535,296 @@ func methodHandlerFunc(file *source.File, t *Template, receiver *types.Named, re
 
 	handlerFunc.Body.List = append(handlerFunc.Body.List, execTemplates)
 
-	if !t.hasResponseWriterArg {
-		handlerFunc.Body.List = append(handlerFunc.Body.List, writeStatusAndHeaders(file, t, resultType, t.defaultStatusCode, statusCodeIdent, bufIdent, resultDataIdent)...)
-	} else {
+	if t.writesBody() {
 		handlerFunc.Body.List = append(handlerFunc.Body.List, callWriteOnResponse(bufIdent))
 	}
 
 	return handlerFunc, nil
 }
 
-func writeBodyAndWriteHeadersFunc(file *source.File, bufIdent, statusCodeIdent string) []ast.Stmt {
-	return []ast.Stmt{
+// templateDataErrorStatusCode declares a TemplateData method returning the status code of the first error
+// in the error chain with a StatusCode method. It returns 0 when there is no such error.
+func templateDataErrorStatusCode(file *source.File, templateDataTypeIdent string) *ast.FuncDecl {
+	const statusCoderIdent = "sc"
+	return &ast.FuncDecl{
+		Recv: templateDataMethodReceiver(templateDataTypeIdent),
+		Name: ast.NewIdent(templateDataErrorStatusCodeMethod),
+		Type: &ast.FuncType{
+			Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("int")}}},
+		},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.DeclStmt{Decl: &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{&ast.ValueSpec{
+				Names: []*ast.Ident{ast.NewIdent(statusCoderIdent)},
+				Type: &ast.InterfaceType{Methods: &ast.FieldList{List: []*ast.Field{{
+					Names: []*ast.Ident{ast.NewIdent("StatusCode")},
+					Type:  &ast.FuncType{Params: &ast.FieldList{}, Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("int")}}}},
+				}}}},
+			}}}},
+			&ast.IfStmt{
+				Cond: file.Call("", "errors", "As", []ast.Expr{
+					&ast.SelectorExpr{X: ast.NewIdent(templateDataReceiverName), Sel: ast.NewIdent(TemplateDataFieldIdentifierError)},
+					&ast.UnaryExpr{Op: token.AND, X: ast.NewIdent(statusCoderIdent)},
+				}),
+				Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{
+					&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(statusCoderIdent), Sel: ast.NewIdent("StatusCode")}},
+				}}}},
+			},
+			&ast.ReturnStmt{Results: []ast.Expr{source.Int(0)}},
+		}},
+	}
+}
+
+// deferRecoverPanicStatement defers a function recovering a panic in the handler.
+// The panic is logged and reported before the error template is rendered with a 500.
+func deferRecoverPanicStatement(file *source.File, t *Template, resultType types.Type, templateDataTypeIdent string) (*ast.DeferStmt, error) {
+	const (
+		recoveredIdent  = "r"
+		resultDataIdent = "rd"
+		zeroValueIdent  = "zv"
+	)
+	typeExpr, err := file.TypeASTExpression(resultType)
+	if err != nil {
+		return nil, err
+	}
+	handlePanicArgs := []ast.Expr{ast.NewIdent(receiverIdent), ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest)}
+	if t.loggerParam {
+		handlePanicArgs = append(handlePanicArgs, ast.NewIdent(loggerParamName))
+	}
+	return &ast.DeferStmt{Call: &ast.CallExpr{Fun: &ast.FuncLit{
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: &ast.BlockStmt{List: []ast.Stmt{&ast.IfStmt{
+			Init: singleAssignment(token.DEFINE, ast.NewIdent(recoveredIdent))(&ast.CallExpr{Fun: ast.NewIdent("recover")}),
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(recoveredIdent), Op: token.NEQ, Y: source.Nil()},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.DeclStmt{Decl: &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{&ast.ValueSpec{
+					Names: []*ast.Ident{ast.NewIdent(zeroValueIdent)},
+					Type:  typeExpr,
+				}}}},
+				singleAssignment(token.DEFINE, ast.NewIdent(resultDataIdent))(newTemplateDataCall(t, templateDataTypeIdent, ast.NewIdent(zeroValueIdent), source.Bool(false), &ast.CallExpr{
+					Fun:  ast.NewIdent(handlePanicFuncIdent(templateDataTypeIdent)),
+					Args: append(handlePanicArgs, ast.NewIdent(recoveredIdent)),
+				})),
+				callRender(t, resultDataIdent, source.HTTPStatusCode(file, http.StatusInternalServerError)),
+			}},
+		}}},
+	}}}, nil
+}
+
+// handlePanicFunc declares the function recovered panics are passed to. It logs the panic with the stack,
+// calls the ReportPanic method when the receiver has one, and returns a generic error for the template
+// so the panic value is not shown to the client.
+// A http.ErrAbortHandler panic is not recovered so the server can abort the response.
+func handlePanicFunc(file *source.File, receiverInterfaceIdent, templateDataTypeIdent string, hasLogger bool) *ast.FuncDecl {
+	const (
+		recoveredIdent = "recovered"
+		stackIdent     = "stack"
+		reporterIdent  = "reporter"
+	)
+	requestContext := func() *ast.CallExpr {
+		return &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Context")}}
+	}
+	params := []*ast.Field{
+		{Names: []*ast.Ident{ast.NewIdent(receiverIdent)}, Type: ast.NewIdent(receiverInterfaceIdent)},
+		httpRequestField(file),
+	}
+	var logger ast.Expr
+	if hasLogger {
+		params = append(params, slogLoggerField(file))
+		logger = ast.NewIdent(loggerParamName)
+	}
+	params = append(params, &ast.Field{Names: []*ast.Ident{ast.NewIdent(recoveredIdent)}, Type: ast.NewIdent("any")})
+	logLine := executeTemplateFailedLogLine(file, logger, handlerPanicMessage, errIdent,
+		file.SlogString("stack", &ast.CallExpr{Fun: ast.NewIdent("string"), Args: []ast.Expr{ast.NewIdent(stackIdent)}}),
+	)
+	reporterType := &ast.InterfaceType{Methods: &ast.FieldList{List: []*ast.Field{{
+		Names: []*ast.Ident{ast.NewIdent(panicReporterMethod)},
+		Type: &ast.FuncType{Params: &ast.FieldList{List: []*ast.Field{
+			{Type: &ast.SelectorExpr{X: ast.NewIdent(file.Import("", "context")), Sel: ast.NewIdent("Context")}},
+			{Type: ast.NewIdent("any")},
+			{Type: &ast.ArrayType{Elt: ast.NewIdent("byte")}},
+		}}},
+	}}}}
+	return &ast.FuncDecl{
+		Name: ast.NewIdent(handlePanicFuncIdent(templateDataTypeIdent)),
+		Type: &ast.FuncType{
+			Params:  &ast.FieldList{List: params},
+			Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("error")}}},
+		},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.IfStmt{
+				Cond: &ast.BinaryExpr{X: ast.NewIdent(recoveredIdent), Op: token.EQL, Y: &ast.SelectorExpr{X: ast.NewIdent(file.AddNetHTTP()), Sel: ast.NewIdent("ErrAbortHandler")}},
+				Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent("panic"), Args: []ast.Expr{ast.NewIdent(recoveredIdent)}}}}},
+			},
+			singleAssignment(token.DEFINE, ast.NewIdent(stackIdent))(file.Call("", "runtime/debug", "Stack", nil)),
+			singleAssignment(token.DEFINE, ast.NewIdent(errIdent))(file.Call("", "fmt", "Errorf", []ast.Expr{source.String("panic: %v"), ast.NewIdent(recoveredIdent)})),
+			&ast.ExprStmt{X: logLine},
+			&ast.IfStmt{
+				Init: &ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent(reporterIdent), ast.NewIdent("ok")},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{&ast.TypeAssertExpr{X: ast.NewIdent(receiverIdent), Type: reporterType}},
+				},
+				Cond: ast.NewIdent("ok"),
+				Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{
+					Fun:  &ast.SelectorExpr{X: ast.NewIdent(reporterIdent), Sel: ast.NewIdent(panicReporterMethod)},
+					Args: []ast.Expr{requestContext(), ast.NewIdent(recoveredIdent), ast.NewIdent(stackIdent)},
+				}}}},
+			},
+			&ast.ReturnStmt{Results: []ast.Expr{file.Call("", "errors", "New", []ast.Expr{source.String(handlerPanicErrorMessage)})}},
+		}},
+	}
+}
+
+// callRender calls the TemplateData render method with the template name, receiver method name, and status code.
+func callRender(t *Template, resultDataIdent string, statusCode ast.Expr) *ast.ExprStmt {
+	return &ast.ExprStmt{X: &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(resultDataIdent), Sel: ast.NewIdent(templateDataRenderMethod)},
+		Args: []ast.Expr{source.String(t.name), source.String(t.Method()), statusCode},
+	}}
+}
+
+// templateDataErrorTemplateName declares a TemplateData method returning the first defined template named
+// for the status code (for example "404"), the status code class ("4xx"), or "error". Otherwise, it returns name.
+func templateDataErrorTemplateName(file *source.File, templateDataTypeIdent, templatesVariableIdent string) *ast.FuncDecl {
+	const (
+		nameIdent       = "name"
+		statusCodeIdent = "statusCode"
+		errorNameIdent  = "errorName"
+	)
+	statusCodeString := func(exp ast.Expr) ast.Expr { return file.StrconvItoaCall(exp) }
+	return &ast.FuncDecl{
+		Recv: templateDataMethodReceiver(templateDataTypeIdent),
+		Name: ast.NewIdent(templateDataErrorTemplateMethod),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{List: []*ast.Field{
+				{Names: []*ast.Ident{ast.NewIdent(nameIdent)}, Type: ast.NewIdent("string")},
+				{Names: []*ast.Ident{ast.NewIdent(statusCodeIdent)}, Type: ast.NewIdent("int")},
+			}},
+			Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("string")}}},
+		},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.RangeStmt{
+				Key:   ast.NewIdent("_"),
+				Value: ast.NewIdent(errorNameIdent),
+				Tok:   token.DEFINE,
+				X: &ast.CompositeLit{Type: &ast.ArrayType{Elt: ast.NewIdent("string")}, Elts: []ast.Expr{
+					statusCodeString(ast.NewIdent(statusCodeIdent)),
+					&ast.BinaryExpr{X: statusCodeString(&ast.BinaryExpr{X: ast.NewIdent(statusCodeIdent), Op: token.QUO, Y: source.Int(100)}), Op: token.ADD, Y: source.String("xx")},
+					source.String("error"),
+				}},
+				Body: &ast.BlockStmt{List: []ast.Stmt{&ast.IfStmt{
+					Cond: &ast.BinaryExpr{
+						X:  &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(templatesVariableIdent), Sel: ast.NewIdent("Lookup")}, Args: []ast.Expr{ast.NewIdent(errorNameIdent)}},
+						Op: token.NEQ,
+						Y:  source.Nil(),
+					},
+					Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent(errorNameIdent)}}}},
+				}}},
+			},
+			&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent(nameIdent)}},
+		}},
+	}
+}
+
+// templateDataRender declares the TemplateData method handlers call to execute a template into a pooled buffer
+// and write the response. The statusCode param is used when the template does not call StatusCode.
+// When errorTemplates is set, an error response renders the error template for the status code of the error.
+func templateDataRender(file *source.File, templateDataTypeIdent, templatesVariableIdent string, hasLogger, errorTemplates bool) *ast.FuncDecl {
+	const (
+		nameIdent           = "name"
+		receiverMethodIdent = "receiverMethod"
+		statusCodeIdent     = "statusCode"
+		bufIdent            = "buf"
+	)
+	dataField := func(name string) *ast.SelectorExpr {
+		return &ast.SelectorExpr{X: ast.NewIdent(templateDataReceiverName), Sel: ast.NewIdent(name)}
+	}
+	var logger ast.Expr
+	if hasLogger {
+		logger = dataField(templateDataFieldLogger)
+	}
+	finalStatusCode := func() ast.Expr {
+		return file.Call("", "cmp", "Or", []ast.Expr{
+			dataField(templateDataFieldStatusCode),
+			&ast.CallExpr{Fun: dataField(templateDataErrorStatusCodeMethod)},
+			ast.NewIdent(statusCodeIdent),
+		})
+	}
+	execTemplate := checkExecuteTemplateError(file, logger,
+		file.SlogString("template", ast.NewIdent(nameIdent)),
+		file.SlogString("receiver_method", ast.NewIdent(receiverMethodIdent)),
+		slogStatusCode(file, finalStatusCode()),
+	)
+	execTemplate.Init = &ast.AssignStmt{
+		Lhs: []ast.Expr{ast.NewIdent(errIdent)},
+		Tok: token.DEFINE,
+		Rhs: []ast.Expr{&ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: ast.NewIdent(templatesVariableIdent), Sel: ast.NewIdent("ExecuteTemplate")},
+			Args: []ast.Expr{ast.NewIdent(bufIdent), ast.NewIdent(nameIdent), ast.NewIdent(templateDataReceiverName)},
+		}},
+	}
+	body := []ast.Stmt{
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse), ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest)},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{dataField(TemplateNameScopeIdentifierHTTPResponse), dataField(TemplateNameScopeIdentifierHTTPRequest)},
+		},
+	}
+	if errorTemplates {
+		body = append(body, &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: dataField(TemplateDataFieldIdentifierError), Op: token.NEQ, Y: source.Nil()},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				singleAssignment(token.ASSIGN, ast.NewIdent(nameIdent))(&ast.CallExpr{
+					Fun: dataField(templateDataErrorTemplateMethod),
+					Args: []ast.Expr{ast.NewIdent(nameIdent), file.Call("", "cmp", "Or", []ast.Expr{
+						&ast.CallExpr{Fun: dataField(templateDataErrorStatusCodeMethod)},
+						ast.NewIdent(statusCodeIdent),
+					})},
+				}),
+			}},
+		})
+	}
+	body = append(body, pooledBufferStatements(file, templateDataTypeIdent, bufIdent)...)
+	body = append(body,
+		execTemplate,
+		singleAssignment(token.ASSIGN, ast.NewIdent(statusCodeIdent))(finalStatusCode()),
+		&ast.IfStmt{ // TODO: make this conditional on a redirect call in the template actions
+			Cond: &ast.BinaryExpr{X: dataField(TemplateDataFieldIdentifierRedirectURL), Op: token.NEQ, Y: source.String("")},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.ExprStmt{X: file.Call("", "net/http", "Redirect", []ast.Expr{
+					ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse),
+					ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest),
+					dataField(TemplateDataFieldIdentifierRedirectURL),
+					ast.NewIdent(statusCodeIdent),
+				})},
+				&ast.ReturnStmt{},
+			}},
+		},
 		setContentTypeHeaderSetOnTemplateData(),
 		&ast.ExprStmt{X: &ast.CallExpr{
 			Fun:  &ast.SelectorExpr{X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse), Sel: ast.NewIdent("Header")}, Args: []ast.Expr{}}, Sel: ast.NewIdent("Set")},
 			Args: []ast.Expr{source.String("content-length"), file.StrconvItoaCall(&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(bufIdent), Sel: ast.NewIdent("Len")}, Args: []ast.Expr{}})},
 		}},
 		callWriteHeader(ast.NewIdent(statusCodeIdent)),
-		callWriteOnResponse(bufIdent),
+		&ast.IfStmt{
+			Cond: &ast.BinaryExpr{
+				X:  &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Method")},
+				Op: token.NEQ,
+				Y:  &ast.SelectorExpr{X: ast.NewIdent(file.AddNetHTTP()), Sel: ast.NewIdent("MethodHead")},
+			},
+			Body: &ast.BlockStmt{List: []ast.Stmt{callWriteOnResponse(bufIdent)}},
+		},
+	)
+	return &ast.FuncDecl{
+		Recv: templateDataMethodReceiver(templateDataTypeIdent),
+		Name: ast.NewIdent(templateDataRenderMethod),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{List: []*ast.Field{
+				{Names: []*ast.Ident{ast.NewIdent(nameIdent), ast.NewIdent(receiverMethodIdent)}, Type: ast.NewIdent("string")},
+				{Names: []*ast.Ident{ast.NewIdent(statusCodeIdent)}, Type: ast.NewIdent("int")},
+			}},
+		},
+		Body: &ast.BlockStmt{List: body},
 	}
 }
 
@@ -426,12 +835,12 @@ func callWriteHeader(statusCode ast.Expr) *ast.ExprStmt {
 	}}
 }
 
-func checkExecuteTemplateError(file *source.File) *ast.IfStmt {
+func checkExecuteTemplateError(file *source.File, logger ast.Expr, attrs ...ast.Expr) *ast.IfStmt {
 	return &ast.IfStmt{
 		Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: source.Nil()},
 		Body: &ast.BlockStmt{
 			List: []ast.Stmt{
-				&ast.ExprStmt{X: executeTemplateFailedLogLine(file, executeTemplateErrorMessage, errIdent)},
+				&ast.ExprStmt{X: executeTemplateFailedLogLine(file, logger, executeTemplateErrorMessage, errIdent, attrs...)},
 				&ast.ExprStmt{X: file.HTTPErrorCall(ast.NewIdent(httpResponseField(file).Names[0].Name), source.String(executeTemplateErrorMessage), http.StatusInternalServerError)},
 				&ast.ReturnStmt{},
 			},
@@ -453,7 +862,20 @@ func callWriteOnResponse(bufferIdent string) *ast.AssignStmt {
 	}
 }
 
-func templateDataType(file *source.File, templateTypeIdent string, receiverType ast.Expr) *ast.GenDecl {
+func templateDataType(file *source.File, templateTypeIdent string, receiverType ast.Expr, hasLogger bool) *ast.GenDecl {
+	fields := []*ast.Field{
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierReceiver)}, Type: receiverType},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse)}, Type: file.HTTPResponseWriter()},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest)}, Type: file.HTTPRequestPtr()},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierResult)}, Type: ast.NewIdent("T")},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierStatusCode)}, Type: ast.NewIdent("int")},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierOkay)}, Type: ast.NewIdent("bool")},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierError)}, Type: ast.NewIdent("error")},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierRedirectURL)}, Type: ast.NewIdent("string")},
+	}
+	if hasLogger {
+		fields = append(fields, slogLoggerField(file))
+	}
 	return &ast.GenDecl{
 		Tok: token.TYPE,
 		Specs: []ast.Spec{
@@ -463,29 +885,39 @@ func templateDataType(file *source.File, templateTypeIdent string, receiverType
 					List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent("T")}, Type: ast.NewIdent("any")}},
 				},
 				Type: &ast.StructType{
-					Fields: &ast.FieldList{
-						List: []*ast.Field{
-							{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierReceiver)}, Type: receiverType},
-							{Names: []*ast.Ident{ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse)}, Type: file.HTTPResponseWriter()},
-							{Names: []*ast.Ident{ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest)}, Type: file.HTTPRequestPtr()},
-							{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierResult)}, Type: ast.NewIdent("T")},
-							{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierStatusCode)}, Type: ast.NewIdent("int")},
-							{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierOkay)}, Type: ast.NewIdent("bool")},
-							{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierError)}, Type: ast.NewIdent("error")},
-							{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierRedirectURL)}, Type: ast.NewIdent("string")},
-						},
-					},
+					Fields: &ast.FieldList{List: fields},
 				},
 			},
 		},
 	}
 }
 
-func newTemplateData(file *source.File, receiverType ast.Expr, templateDataTypeIdent string) *ast.FuncDecl {
+func newTemplateData(file *source.File, receiverType ast.Expr, templateDataTypeIdent string, hasLogger bool) *ast.FuncDecl {
 	const (
 		okayIdent       = "okay"
 		resultParamName = "result"
 	)
+	params := []*ast.Field{
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierReceiver)}, Type: receiverType},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse)}, Type: file.HTTPResponseWriter()},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest)}, Type: file.HTTPRequestPtr()},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierResult)}, Type: ast.NewIdent("T")},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierOkay)}, Type: ast.NewIdent("bool")},
+		{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierError)}, Type: ast.NewIdent("error")},
+	}
+	fields := []ast.Expr{
+		&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierReceiver), Value: ast.NewIdent(TemplateDataFieldIdentifierReceiver)},
+		&ast.KeyValueExpr{Key: ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse), Value: ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse)},
+		&ast.KeyValueExpr{Key: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Value: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest)},
+		&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierResult), Value: ast.NewIdent(resultParamName)},
+		&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierOkay), Value: ast.NewIdent(okayIdent)},
+		&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierError), Value: ast.NewIdent(TemplateDataFieldIdentifierError)},
+		&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierRedirectURL), Value: source.String("")},
+	}
+	if hasLogger {
+		params = append(params, slogLoggerField(file))
+		fields = append(fields, &ast.KeyValueExpr{Key: ast.NewIdent(templateDataFieldLogger), Value: ast.NewIdent(loggerParamName)})
+	}
 	return &ast.FuncDecl{
 		Name: ast.NewIdent(newResponseDataFuncIdent(templateDataTypeIdent)),
 		Type: &ast.FuncType{
@@ -494,16 +926,7 @@ func newTemplateData(file *source.File, receiverType ast.Expr, templateDataTypeI
 					{Names: []*ast.Ident{ast.NewIdent("T")}, Type: ast.NewIdent("any")},
 				},
 			},
-			Params: &ast.FieldList{
-				List: []*ast.Field{
-					{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierReceiver)}, Type: receiverType},
-					{Names: []*ast.Ident{ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse)}, Type: file.HTTPResponseWriter()},
-					{Names: []*ast.Ident{ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest)}, Type: file.HTTPRequestPtr()},
-					{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierResult)}, Type: ast.NewIdent("T")},
-					{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierOkay)}, Type: ast.NewIdent("bool")},
-					{Names: []*ast.Ident{ast.NewIdent(TemplateDataFieldIdentifierError)}, Type: ast.NewIdent("error")},
-				},
-			},
+			Params: &ast.FieldList{List: params},
 			Results: &ast.FieldList{
 				List: []*ast.Field{
 					{Type: &ast.StarExpr{X: &ast.IndexExpr{
@@ -522,15 +945,7 @@ func newTemplateData(file *source.File, receiverType ast.Expr, templateDataTypeI
 								X:     ast.NewIdent(templateDataTypeIdent),
 								Index: ast.NewIdent("T"),
 							},
-							Elts: []ast.Expr{
-								&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierReceiver), Value: ast.NewIdent(TemplateDataFieldIdentifierReceiver)},
-								&ast.KeyValueExpr{Key: ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse), Value: ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse)},
-								&ast.KeyValueExpr{Key: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Value: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest)},
-								&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierResult), Value: ast.NewIdent(resultParamName)},
-								&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierOkay), Value: ast.NewIdent(okayIdent)},
-								&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierError), Value: ast.NewIdent(TemplateDataFieldIdentifierError)},
-								&ast.KeyValueExpr{Key: ast.NewIdent(TemplateDataFieldIdentifierRedirectURL), Value: source.String("")},
-							},
+							Elts: fields,
 						}},
 					},
 				},
@@ -539,6 +954,59 @@ func newTemplateData(file *source.File, receiverType ast.Expr, templateDataTypeI
 	}
 }
 
+// newTemplateDataCall calls the TemplateData constructor from a handler.
+// The routes function logger is passed along when it has one.
+func newTemplateDataCall(t *Template, templateDataTypeIdent string, result, okay, err ast.Expr) *ast.CallExpr {
+	args := []ast.Expr{
+		ast.NewIdent(receiverIdent),
+		ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse),
+		ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest),
+		result,
+		okay,
+		err,
+	}
+	if t.loggerParam {
+		args = append(args, ast.NewIdent(loggerParamName))
+	}
+	return &ast.CallExpr{Fun: ast.NewIdent(newResponseDataFuncIdent(templateDataTypeIdent)), Args: args}
+}
+
+const middlewareNextParamName = "next"
+
+// middlewareFuncType is the type of the routes function middleware parameter.
+// The default middleware only uses the next parameter so the others are blank.
+func middlewareFuncType(file *source.File, isDefault bool) *ast.FuncType {
+	httpHandler := func() ast.Expr {
+		return &ast.SelectorExpr{X: ast.NewIdent(file.AddNetHTTP()), Sel: ast.NewIdent("Handler")}
+	}
+	names := []*ast.Ident{ast.NewIdent("pattern"), ast.NewIdent("receiverMethod")}
+	next := ast.NewIdent(middlewareNextParamName)
+	if isDefault {
+		names = []*ast.Ident{ast.NewIdent("_"), ast.NewIdent("_")}
+	}
+	return &ast.FuncType{
+		Params: &ast.FieldList{List: []*ast.Field{
+			{Names: names, Type: ast.NewIdent("string")},
+			{Names: []*ast.Ident{next}, Type: httpHandler()},
+		}},
+		Results: &ast.FieldList{List: []*ast.Field{{Type: httpHandler()}}},
+	}
+}
+
+func middlewareField(file *source.File) *ast.Field {
+	return &ast.Field{
+		Names: []*ast.Ident{ast.NewIdent(middlewareParamName)},
+		Type:  middlewareFuncType(file, false),
+	}
+}
+
+func slogLoggerField(file *source.File) *ast.Field {
+	return &ast.Field{
+		Names: []*ast.Ident{ast.NewIdent(loggerParamName)},
+		Type:  &ast.StarExpr{X: &ast.SelectorExpr{X: ast.NewIdent(file.Import("", "log/slog")), Sel: ast.NewIdent("Logger")}},
+	}
+}
+
 const (
 	templateDataReceiverName = "data"
 )
@@ -584,6 +1052,286 @@ func templateDataError(templateDataTypeIdent string) *ast.FuncDecl {
 	}
 }
 
+// templateDataAcceptsJSON reports whether the request Accept header ranks application/json higher than
+// text/html (or */* when text/html is not listed). A media range with a quality value of zero is not acceptable.
+func templateDataAcceptsJSON(file *source.File, templateDataTypeIdent string) *ast.FuncDecl {
+	const (
+		acceptIdent     = "accept"
+		mediaRangeIdent = "mediaRange"
+		mediaTypeIdent  = "mediaType"
+		paramsIdent     = "params"
+		qualityIdent    = "q"
+		jsonIdent       = "jsonQ"
+		htmlIdent       = "htmlQ"
+		anyIdent        = "anyQ"
+	)
+	maxQuality := func(ident string) ast.Stmt {
+		return singleAssignment(token.ASSIGN, ast.NewIdent(ident))(&ast.CallExpr{Fun: ast.NewIdent("max"), Args: []ast.Expr{ast.NewIdent(ident), ast.NewIdent(qualityIdent)}})
+	}
+	return &ast.FuncDecl{
+		Recv: templateDataMethodReceiver(templateDataTypeIdent),
+		Name: ast.NewIdent(templateDataAcceptsJSONMethod),
+		Type: &ast.FuncType{
+			Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("bool")}}},
+		},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ast.NewIdent(jsonIdent), ast.NewIdent(htmlIdent), ast.NewIdent(anyIdent)},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{
+					&ast.BasicLit{Kind: token.FLOAT, Value: "0.0"},
+					&ast.UnaryExpr{Op: token.SUB, X: &ast.BasicLit{Kind: token.FLOAT, Value: "1.0"}},
+					&ast.BasicLit{Kind: token.FLOAT, Value: "0.0"},
+				},
+			},
+			&ast.RangeStmt{
+				Key:   ast.NewIdent("_"),
+				Value: ast.NewIdent(acceptIdent),
+				Tok:   token.DEFINE,
+				X: &ast.CallExpr{
+					Fun: &ast.SelectorExpr{
+						X:   &ast.SelectorExpr{X: &ast.SelectorExpr{X: ast.NewIdent(templateDataReceiverName), Sel: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest)}, Sel: ast.NewIdent("Header")},
+						Sel: ast.NewIdent("Values"),
+					},
+					Args: []ast.Expr{source.String("Accept")},
+				},
+				Body: &ast.BlockStmt{List: []ast.Stmt{
+					&ast.RangeStmt{
+						Key:   ast.NewIdent("_"),
+						Value: ast.NewIdent(mediaRangeIdent),
+						Tok:   token.DEFINE,
+						X:     file.Call("", "strings", "Split", []ast.Expr{ast.NewIdent(acceptIdent), source.String(",")}),
+						Body: &ast.BlockStmt{List: []ast.Stmt{
+							&ast.AssignStmt{
+								Lhs: []ast.Expr{ast.NewIdent(mediaTypeIdent), ast.NewIdent(paramsIdent), ast.NewIdent("_")},
+								Tok: token.DEFINE,
+								Rhs: []ast.Expr{file.Call("", "mime", "ParseMediaType", []ast.Expr{ast.NewIdent(mediaRangeIdent)})},
+							},
+							&ast.AssignStmt{
+								Lhs: []ast.Expr{ast.NewIdent(qualityIdent), ast.NewIdent(errIdent)},
+								Tok: token.DEFINE,
+								Rhs: []ast.Expr{file.Call("", "strconv", "ParseFloat", []ast.Expr{
+									file.Call("", "cmp", "Or", []ast.Expr{&ast.IndexExpr{X: ast.NewIdent(paramsIdent), Index: source.String("q")}, source.String("1")}),
+									source.Int(64),
+								})},
+							},
+							&ast.IfStmt{
+								Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: source.Nil()},
+								Body: &ast.BlockStmt{List: []ast.Stmt{&ast.BranchStmt{Tok: token.CONTINUE}}},
+							},
+							&ast.SwitchStmt{
+								Tag: ast.NewIdent(mediaTypeIdent),
+								Body: &ast.BlockStmt{List: []ast.Stmt{
+									&ast.CaseClause{List: []ast.Expr{source.String(jsonContentType)}, Body: []ast.Stmt{maxQuality(jsonIdent)}},
+									&ast.CaseClause{List: []ast.Expr{source.String("text/html")}, Body: []ast.Stmt{maxQuality(htmlIdent)}},
+									&ast.CaseClause{List: []ast.Expr{source.String("*/*")}, Body: []ast.Stmt{maxQuality(anyIdent)}},
+								}},
+							},
+						}},
+					},
+				}},
+			},
+			&ast.IfStmt{
+				Cond: &ast.BinaryExpr{X: ast.NewIdent(htmlIdent), Op: token.LSS, Y: source.Int(0)},
+				Body: &ast.BlockStmt{List: []ast.Stmt{singleAssignment(token.ASSIGN, ast.NewIdent(htmlIdent))(ast.NewIdent(anyIdent))}},
+			},
+			&ast.ReturnStmt{Results: []ast.Expr{&ast.BinaryExpr{X: ast.NewIdent(jsonIdent), Op: token.GTR, Y: ast.NewIdent(htmlIdent)}}},
+		}},
+	}
+}
+
+// templateDataWriteJSON writes the result, or an object with the error message when there is an error, as the JSON response body.
+// For a 5xx response, the message is the status text unless the error has a StatusCode method.
+func templateDataWriteJSON(file *source.File, templateDataTypeIdent string, hasLogger bool) *ast.FuncDecl {
+	const (
+		statusCodeIdent = "statusCode"
+		bodyIdent       = "body"
+		bufIdent        = "buf"
+		messageIdent    = "message"
+		message         = "failed to encode JSON"
+	)
+	dataField := func(name string) *ast.SelectorExpr {
+		return &ast.SelectorExpr{X: ast.NewIdent(templateDataReceiverName), Sel: ast.NewIdent(name)}
+	}
+	setHeader := func(key string, value ast.Expr) ast.Stmt {
+		return &ast.ExprStmt{X: &ast.CallExpr{
+			Fun:  &ast.SelectorExpr{X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: dataField(TemplateNameScopeIdentifierHTTPResponse), Sel: ast.NewIdent("Header")}}, Sel: ast.NewIdent("Set")},
+			Args: []ast.Expr{source.String(key), value},
+		}}
+	}
+	var logger ast.Expr
+	if hasLogger {
+		logger = dataField(templateDataFieldLogger)
+	}
+	return &ast.FuncDecl{
+		Recv: templateDataMethodReceiver(templateDataTypeIdent),
+		Name: ast.NewIdent(templateDataWriteJSONMethod),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(statusCodeIdent)}, Type: ast.NewIdent("int")}}},
+		},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			singleAssignment(token.ASSIGN, ast.NewIdent(statusCodeIdent))(file.Call("", "cmp", "Or", []ast.Expr{
+				&ast.CallExpr{Fun: dataField(templateDataErrorStatusCodeMethod)},
+				ast.NewIdent(statusCodeIdent),
+			})),
+			&ast.DeclStmt{Decl: &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{&ast.ValueSpec{
+				Names:  []*ast.Ident{ast.NewIdent(bodyIdent)},
+				Type:   ast.NewIdent("any"),
+				Values: []ast.Expr{dataField(TemplateDataFieldIdentifierResult)},
+			}}}},
+			&ast.IfStmt{
+				Cond: &ast.BinaryExpr{X: dataField(TemplateDataFieldIdentifierError), Op: token.NEQ, Y: source.Nil()},
+				Body: &ast.BlockStmt{List: []ast.Stmt{
+					singleAssignment(token.DEFINE, ast.NewIdent(messageIdent))(&ast.CallExpr{Fun: &ast.SelectorExpr{X: dataField(TemplateDataFieldIdentifierError), Sel: ast.NewIdent("Error")}}),
+					&ast.IfStmt{
+						Cond: &ast.BinaryExpr{
+							X:  &ast.BinaryExpr{X: ast.NewIdent(statusCodeIdent), Op: token.GEQ, Y: source.HTTPStatusCode(file, http.StatusInternalServerError)},
+							Op: token.LAND,
+							Y:  &ast.BinaryExpr{X: &ast.CallExpr{Fun: dataField(templateDataErrorStatusCodeMethod)}, Op: token.EQL, Y: source.Int(0)},
+						},
+						Body: &ast.BlockStmt{List: []ast.Stmt{
+							singleAssignment(token.ASSIGN, ast.NewIdent(messageIdent))(file.Call("", "net/http", "StatusText", []ast.Expr{ast.NewIdent(statusCodeIdent)})),
+						}},
+					},
+					singleAssignment(token.ASSIGN, ast.NewIdent(bodyIdent))(&ast.CompositeLit{
+						Type: &ast.MapType{Key: ast.NewIdent("string"), Value: ast.NewIdent("string")},
+						Elts: []ast.Expr{&ast.KeyValueExpr{
+							Key:   source.String("error"),
+							Value: ast.NewIdent(messageIdent),
+						}},
+					}),
+				}},
+			},
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ast.NewIdent(bufIdent), ast.NewIdent(errIdent)},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{file.Call("", "encoding/json", "Marshal", []ast.Expr{ast.NewIdent(bodyIdent)})},
+			},
+			&ast.IfStmt{
+				Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: source.Nil()},
+				Body: &ast.BlockStmt{List: []ast.Stmt{
+					&ast.ExprStmt{X: logErrorContextCall(file, logger, []ast.Expr{
+						&ast.CallExpr{Fun: &ast.SelectorExpr{X: dataField(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent(httpRequestContextMethod)}},
+						source.String(message),
+						file.SlogString("method", &ast.SelectorExpr{X: dataField(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Method")}),
+						file.SlogString("path", &ast.SelectorExpr{X: &ast.SelectorExpr{X: dataField(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("URL")}, Sel: ast.NewIdent("Path")}),
+						file.SlogString("pattern", &ast.SelectorExpr{X: dataField(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Pattern")}),
+						file.Call("", "log/slog", "Int", []ast.Expr{source.String("status_code"), ast.NewIdent(statusCodeIdent)}),
+						file.SlogString("error", source.CallError(errIdent)),
+					})},
+					&ast.ExprStmt{X: file.HTTPErrorCall(dataField(TemplateNameScopeIdentifierHTTPResponse), source.String(message), http.StatusInternalServerError)},
+					&ast.ReturnStmt{},
+				}},
+			},
+			setHeader("content-type", source.String(jsonContentType)),
+			setHeader("content-length", file.StrconvItoaCall(&ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{ast.NewIdent(bufIdent)}})),
+			&ast.ExprStmt{X: &ast.CallExpr{
+				Fun:  &ast.SelectorExpr{X: dataField(TemplateNameScopeIdentifierHTTPResponse), Sel: ast.NewIdent("WriteHeader")},
+				Args: []ast.Expr{ast.NewIdent(statusCodeIdent)},
+			}},
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{ast.NewIdent("_"), ast.NewIdent("_")},
+				Tok: token.ASSIGN,
+				Rhs: []ast.Expr{&ast.CallExpr{
+					Fun:  &ast.SelectorExpr{X: dataField(TemplateNameScopeIdentifierHTTPResponse), Sel: ast.NewIdent("Write")},
+					Args: []ast.Expr{ast.NewIdent(bufIdent)},
+				}},
+			},
+		}},
+	}
+}
+
+// validationErrorsDecls declares the validation errors type (named for the template data type, like
+// TemplateDataValidationErrors) used when AggregateValidationErrors is set and a TemplateData method
+// returning the validation errors for the request.
+func validationErrorsDecls(file *source.File, templateDataTypeIdent string) []ast.Decl {
+	const (
+		errsIdent     = "errs"
+		nameIdent     = "name"
+		namesIdent    = "names"
+		messageIdent  = "message"
+		messagesIdent = "messages"
+	)
+	typeIdent := validationErrorsTypeIdent(templateDataTypeIdent)
+	errsReceiver := &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(errsIdent)}, Type: ast.NewIdent(typeIdent)}}}
+	errsIndexName := &ast.IndexExpr{X: ast.NewIdent(errsIdent), Index: ast.NewIdent(nameIdent)}
+	return []ast.Decl{
+		&ast.GenDecl{
+			Tok: token.TYPE,
+			Specs: []ast.Spec{&ast.TypeSpec{
+				Name: ast.NewIdent(typeIdent),
+				Type: &ast.MapType{Key: ast.NewIdent("string"), Value: &ast.ArrayType{Elt: ast.NewIdent("string")}},
+			}},
+		},
+		&ast.FuncDecl{
+			Recv: errsReceiver,
+			Name: ast.NewIdent("Add"),
+			Type: &ast.FuncType{Params: &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(nameIdent), ast.NewIdent(messageIdent)}, Type: ast.NewIdent("string")}}}},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				singleAssignment(token.ASSIGN, errsIndexName)(&ast.CallExpr{Fun: ast.NewIdent("append"), Args: []ast.Expr{errsIndexName, ast.NewIdent(messageIdent)}}),
+			}},
+		},
+		&ast.FuncDecl{
+			Recv: errsReceiver,
+			Name: ast.NewIdent("Field"),
+			Type: &ast.FuncType{
+				Params:  &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(nameIdent)}, Type: ast.NewIdent("string")}}},
+				Results: &ast.FieldList{List: []*ast.Field{{Type: &ast.ArrayType{Elt: ast.NewIdent("string")}}}},
+			},
+			Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{errsIndexName}}}},
+		},
+		&ast.FuncDecl{
+			Recv: errsReceiver,
+			Name: ast.NewIdent("Error"),
+			Type: &ast.FuncType{Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("string")}}}},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				singleAssignment(token.DEFINE, ast.NewIdent(namesIdent))(&ast.CallExpr{Fun: ast.NewIdent("make"), Args: []ast.Expr{
+					&ast.ArrayType{Elt: ast.NewIdent("string")},
+					source.Int(0),
+					&ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{ast.NewIdent(errsIdent)}},
+				}}),
+				&ast.RangeStmt{
+					Key: ast.NewIdent(nameIdent),
+					Tok: token.DEFINE,
+					X:   ast.NewIdent(errsIdent),
+					Body: &ast.BlockStmt{List: []ast.Stmt{
+						singleAssignment(token.ASSIGN, ast.NewIdent(namesIdent))(&ast.CallExpr{Fun: ast.NewIdent("append"), Args: []ast.Expr{ast.NewIdent(namesIdent), ast.NewIdent(nameIdent)}}),
+					}},
+				},
+				&ast.ExprStmt{X: file.Call("", "slices", "Sort", []ast.Expr{ast.NewIdent(namesIdent)})},
+				&ast.DeclStmt{Decl: &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{
+					&ast.ValueSpec{Names: []*ast.Ident{ast.NewIdent(messagesIdent)}, Type: &ast.ArrayType{Elt: ast.NewIdent("string")}},
+				}}},
+				&ast.RangeStmt{
+					Key:   ast.NewIdent("_"),
+					Value: ast.NewIdent(nameIdent),
+					Tok:   token.DEFINE,
+					X:     ast.NewIdent(namesIdent),
+					Body: &ast.BlockStmt{List: []ast.Stmt{
+						singleAssignment(token.ASSIGN, ast.NewIdent(messagesIdent))(&ast.CallExpr{Fun: ast.NewIdent("append"), Args: []ast.Expr{ast.NewIdent(messagesIdent), errsIndexName}, Ellipsis: 1}),
+					}},
+				},
+				&ast.ReturnStmt{Results: []ast.Expr{file.Call("", "strings", "Join", []ast.Expr{ast.NewIdent(messagesIdent), source.String("; ")})}},
+			}},
+		},
+		&ast.FuncDecl{
+			Recv: templateDataMethodReceiver(templateDataTypeIdent),
+			Name: ast.NewIdent(templateDataValidationErrorsMethod),
+			Type: &ast.FuncType{Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent(typeIdent)}}}},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.DeclStmt{Decl: &ast.GenDecl{Tok: token.VAR, Specs: []ast.Spec{
+					&ast.ValueSpec{Names: []*ast.Ident{ast.NewIdent(errsIdent)}, Type: ast.NewIdent(typeIdent)},
+				}}},
+				&ast.ExprStmt{X: file.Call("", "errors", "As", []ast.Expr{
+					&ast.SelectorExpr{X: ast.NewIdent(templateDataReceiverName), Sel: ast.NewIdent(TemplateDataFieldIdentifierError)},
+					&ast.UnaryExpr{Op: token.AND, X: ast.NewIdent(errsIdent)},
+				})},
+				&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent(errsIdent)}},
+			}},
+		},
+	}
+}
+
 func templateDataReceiver(receiverType ast.Expr, templateDataTypeIdent string) *ast.FuncDecl {
 	return &ast.FuncDecl{
 		Recv: templateDataMethodReceiver(templateDataTypeIdent),
@@ -852,7 +1600,7 @@ func setContentTypeHeaderSetOnTemplateData() *ast.IfStmt {
 	}
 }
 
-func appendParseArgumentStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, sigs map[string]*types.Signature, parsed map[string]struct{}, receiver *types.Named, templateDataTypeIdent, templatesVariableIdent string, call *ast.CallExpr, validationFailureBlock source.ValidationErrorBlock) ([]ast.Stmt, error) {
+func appendParseArgumentStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, sigs map[string]*types.Signature, parsed map[string]struct{}, receiver *types.Named, templateDataTypeIdent, templatesVariableIdent string, config RoutesFileConfiguration, call *ast.CallExpr, validationFailureBlock source.ValidationErrorBlock) ([]ast.Stmt, error) {
 	fun, ok := call.Fun.(*ast.Ident)
 	if !ok {
 		return nil, fmt.Errorf("expected function to be identifier")
@@ -877,7 +1625,7 @@ func appendParseArgumentStatements(statements []ast.Stmt, t *Template, file *sou
 		default:
 			// TODO: add error case
 		case *ast.CallExpr:
-			parseArgStatements, err := appendParseArgumentStatements(statements, t, file, resultType, sigs, parsed, receiver, templateDataTypeIdent, templatesVariableIdent, arg, validationFailureBlock)
+			parseArgStatements, err := appendParseArgumentStatements(statements, t, file, resultType, sigs, parsed, receiver, templateDataTypeIdent, templatesVariableIdent, config, arg, validationFailureBlock)
 			if err != nil {
 				return nil, err
 			}
@@ -933,8 +1681,21 @@ func appendParseArgumentStatements(statements []ast.Stmt, t *Template, file *sou
 							return nil, err
 						}
 						statements = append(statements, callParseForm(), declareFormVar)
+					case TemplateNameScopeIdentifierQuery:
+						statements = append(statements, singleAssignment(token.DEFINE, ast.NewIdent(arg.Name))(requestURLQueryCall()))
+						t.queryType = param.Type()
+					case TemplateNameScopeIdentifierHeader:
+						statements = append(statements, singleAssignment(token.DEFINE, ast.NewIdent(arg.Name))(&ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Header")}))
+					case TemplateNameScopeIdentifierCookie:
+						statements = append(statements, singleAssignment(token.DEFINE, ast.NewIdent(arg.Name))(&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Cookies")}}))
 					case TemplateNameScopeIdentifierContext:
 						statements = append(statements, contextAssignment(TemplateNameScopeIdentifierContext))
+					case TemplateNameScopeIdentifierBody:
+						s, err := appendDecodeJSONBodyStatements(statements, t, file, resultType, arg, param, config.BodyMaxBytes, templateDataTypeIdent, templatesVariableIdent)
+						if err != nil {
+							return nil, err
+						}
+						statements = s
 					default:
 						if slices.Contains(t.parsePathValueNames(), arg.Name) {
 							statements = append(statements, singleAssignment(token.DEFINE, ast.NewIdent(arg.Name))(src))
@@ -949,14 +1710,47 @@ func appendParseArgumentStatements(statements []ast.Stmt, t *Template, file *sou
 			switch {
 			case slices.Contains(t.parsePathValueNames(), arg.Name):
 				parsed[arg.Name] = struct{}{}
-				s, err := generateParseValueFromStringStatements(file, t, arg.Name+"Parsed", resultType, src, param.Type(), nil, singleAssignment(token.DEFINE, ast.NewIdent(arg.Name)), templateDataTypeIdent, templatesVariableIdent)
+				errBlock, err := errorResultBlock(file, t, resultType, http.StatusBadRequest, templateDataTypeIdent, templatesVariableIdent, ast.NewIdent(errIdent))
+				if err != nil {
+					return nil, err
+				}
+				s, err := generateParseValueFromStringStatements(file, arg.Name+"Parsed", src, param.Type(), nil, errBlock, singleAssignment(token.DEFINE, ast.NewIdent(arg.Name)))
 				if err != nil {
 					return nil, err
 				}
 				statements = append(statements, s...)
 				t.pathValueTypes[arg.Name] = param.Type()
 			case arg.Name == TemplateNameScopeIdentifierForm:
-				s, err := appendParseFormToStructStatements(statements, t, file, resultType, arg, param, validationFailureBlock, templateDataTypeIdent, templatesVariableIdent)
+				s, err := appendParseFormToStructStatements(statements, t, file, resultType, arg, param, config.MultipartMaxMemory, config.AggregateValidationErrors, validationFailureBlock, templateDataTypeIdent, templatesVariableIdent)
+				if err != nil {
+					return nil, err
+				}
+				statements = s
+			case arg.Name == TemplateNameScopeIdentifierQuery:
+				parsed[arg.Name] = struct{}{}
+				t.queryType = param.Type()
+				s, err := appendParseQueryToStructStatements(statements, t, file, resultType, arg, param, config.AggregateValidationErrors, validationFailureBlock, templateDataTypeIdent, templatesVariableIdent)
+				if err != nil {
+					return nil, err
+				}
+				statements = s
+			case arg.Name == TemplateNameScopeIdentifierHeader:
+				parsed[arg.Name] = struct{}{}
+				s, err := appendParseHeaderToStructStatements(statements, t, file, resultType, arg, param, config.AggregateValidationErrors, validationFailureBlock, templateDataTypeIdent, templatesVariableIdent)
+				if err != nil {
+					return nil, err
+				}
+				statements = s
+			case arg.Name == TemplateNameScopeIdentifierBody:
+				parsed[arg.Name] = struct{}{}
+				s, err := appendDecodeJSONBodyStatements(statements, t, file, resultType, arg, param, config.BodyMaxBytes, templateDataTypeIdent, templatesVariableIdent)
+				if err != nil {
+					return nil, err
+				}
+				statements = s
+			case arg.Name == TemplateNameScopeIdentifierCookie:
+				parsed[arg.Name] = struct{}{}
+				s, err := appendParseCookieToStructStatements(statements, t, file, resultType, arg, param, config.AggregateValidationErrors, validationFailureBlock, templateDataTypeIdent, templatesVariableIdent)
 				if err != nil {
 					return nil, err
 				}
@@ -967,42 +1761,434 @@ func appendParseArgumentStatements(statements []ast.Stmt, t *Template, file *sou
 				return nil, fmt.Errorf("method expects type %s but %s is %s", source.Format(pt), arg.Name, source.Format(at))
 			}
 		}
-	}
-	return statements, nil
-}
-
-func appendParseFormToStructStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, arg *ast.Ident, param types.Object, validationBlock source.ValidationErrorBlock, templateDataTypeIdent, templatesVariableIdent string) ([]ast.Stmt, error) {
-	const parsedVariableName = "value"
-	statements = append(statements, callParseForm())
-
-	declareFormVar, err := formVariableDeclaration(file, arg, param.Type())
-	if err != nil {
-		return nil, err
-	}
-	statements = append(statements, declareFormVar)
-
-	form, ok := param.Type().Underlying().(*types.Struct)
-	if !ok {
-		return nil, fmt.Errorf("expected form parameter type to be a struct")
-	}
-
-	for i := 0; i < form.NumFields(); i++ {
-		field, tags := form.Field(i), reflect.StructTag(form.Tag(i))
-		inputName := field.Name()
-		if name, found := tags.Lookup(InputAttributeNameStructTag); found {
-			inputName = name
+	}
+	return statements, nil
+}
+
+func appendParseFormToStructStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, arg *ast.Ident, param types.Object, multipartMaxMemory int64, aggregateValidationErrors bool, validationBlock source.ValidationErrorBlock, templateDataTypeIdent, templatesVariableIdent string) ([]ast.Stmt, error) {
+	var files func(name string) ast.Expr
+	if st, ok := param.Type().Underlying().(*types.Struct); ok && hasMultipartFileFields(file, st) {
+		errBlock, err := errorResultBlock(file, t, resultType, http.StatusBadRequest, templateDataTypeIdent, templatesVariableIdent, ast.NewIdent(errIdent))
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, &ast.IfStmt{
+			Init: &ast.AssignStmt{
+				Lhs: []ast.Expr{ast.NewIdent(errIdent)},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{&ast.CallExpr{
+					Fun:  &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("ParseMultipartForm")},
+					Args: []ast.Expr{byteSizeExpr(multipartMaxMemory)},
+				}},
+			},
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: source.Nil()},
+			Body: errBlock,
+		})
+		files = func(name string) ast.Expr {
+			return &ast.IndexExpr{
+				X:     &ast.SelectorExpr{X: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("MultipartForm")}, Sel: ast.NewIdent("File")},
+				Index: source.String(name),
+			}
+		}
+	} else {
+		statements = append(statements, callParseForm())
+	}
+
+	declareFormVar, err := formVariableDeclaration(file, arg, param.Type())
+	if err != nil {
+		return nil, err
+	}
+	statements = append(statements, declareFormVar)
+
+	return appendParseStructFieldsStatements(statements, t, file, resultType, arg, param, structFieldValues{
+		tag: InputAttributeNameStructTag,
+		value: func(name string) ast.Expr {
+			return &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("FormValue")}, Args: []ast.Expr{source.String(name)}}
+		},
+		values: func(name string) ast.Expr {
+			return &ast.IndexExpr{X: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Form")}, Index: source.String(name)}
+		},
+		files: files,
+		missing: func(name string) ast.Expr {
+			return &ast.BinaryExpr{
+				X:  &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("FormValue")}, Args: []ast.Expr{source.String(name)}},
+				Op: token.EQL,
+				Y:  source.String(""),
+			}
+		},
+	}, aggregateValidationErrors, validationBlock, templateDataTypeIdent, templatesVariableIdent)
+}
+
+func appendParseQueryToStructStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, arg *ast.Ident, param types.Object, aggregateValidationErrors bool, validationBlock source.ValidationErrorBlock, templateDataTypeIdent, templatesVariableIdent string) ([]ast.Stmt, error) {
+	const queryValuesIdent = "queryValues"
+	statements = append(statements, singleAssignment(token.DEFINE, ast.NewIdent(queryValuesIdent))(requestURLQueryCall()))
+
+	declareQueryVar, err := formVariableDeclaration(file, arg, param.Type())
+	if err != nil {
+		return nil, err
+	}
+	statements = append(statements, declareQueryVar)
+
+	return appendParseStructFieldsStatements(statements, t, file, resultType, arg, param, structFieldValues{
+		tag: InputAttributeNameStructTag,
+		value: func(name string) ast.Expr {
+			return &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(queryValuesIdent), Sel: ast.NewIdent("Get")}, Args: []ast.Expr{source.String(name)}}
+		},
+		values: func(name string) ast.Expr {
+			return &ast.IndexExpr{X: ast.NewIdent(queryValuesIdent), Index: source.String(name)}
+		},
+		missing: func(name string) ast.Expr {
+			return &ast.BinaryExpr{
+				X:  &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(queryValuesIdent), Sel: ast.NewIdent("Get")}, Args: []ast.Expr{source.String(name)}},
+				Op: token.EQL,
+				Y:  source.String(""),
+			}
+		},
+		ifPresent: func(name string, body []ast.Stmt) ast.Stmt {
+			return &ast.IfStmt{
+				Cond: &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(queryValuesIdent), Sel: ast.NewIdent("Has")}, Args: []ast.Expr{source.String(name)}},
+				Body: &ast.BlockStmt{List: body},
+			}
+		},
+	}, aggregateValidationErrors, validationBlock, templateDataTypeIdent, templatesVariableIdent)
+}
+
+func appendParseHeaderToStructStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, arg *ast.Ident, param types.Object, aggregateValidationErrors bool, validationBlock source.ValidationErrorBlock, templateDataTypeIdent, templatesVariableIdent string) ([]ast.Stmt, error) {
+	declareHeaderVar, err := formVariableDeclaration(file, arg, param.Type())
+	if err != nil {
+		return nil, err
+	}
+	statements = append(statements, declareHeaderVar)
+
+	requestHeader := &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Header")}
+	return appendParseStructFieldsStatements(statements, t, file, resultType, arg, param, structFieldValues{
+		tag: HeaderNameStructTag,
+		value: func(name string) ast.Expr {
+			return &ast.CallExpr{Fun: &ast.SelectorExpr{X: requestHeader, Sel: ast.NewIdent("Get")}, Args: []ast.Expr{source.String(name)}}
+		},
+		values: func(name string) ast.Expr {
+			return &ast.CallExpr{Fun: &ast.SelectorExpr{X: requestHeader, Sel: ast.NewIdent("Values")}, Args: []ast.Expr{source.String(name)}}
+		},
+		missing: func(name string) ast.Expr {
+			return &ast.BinaryExpr{
+				X:  &ast.CallExpr{Fun: &ast.SelectorExpr{X: requestHeader, Sel: ast.NewIdent("Get")}, Args: []ast.Expr{source.String(name)}},
+				Op: token.EQL,
+				Y:  source.String(""),
+			}
+		},
+		ifPresent: func(name string, body []ast.Stmt) ast.Stmt {
+			return &ast.IfStmt{
+				Cond: &ast.BinaryExpr{
+					X:  &ast.CallExpr{Fun: &ast.SelectorExpr{X: requestHeader, Sel: ast.NewIdent("Get")}, Args: []ast.Expr{source.String(name)}},
+					Op: token.NEQ,
+					Y:  source.String(""),
+				},
+				Body: &ast.BlockStmt{List: body},
+			}
+		},
+	}, aggregateValidationErrors, validationBlock, templateDataTypeIdent, templatesVariableIdent)
+}
+
+func appendParseCookieToStructStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, arg *ast.Ident, param types.Object, aggregateValidationErrors bool, validationBlock source.ValidationErrorBlock, templateDataTypeIdent, templatesVariableIdent string) ([]ast.Stmt, error) {
+	const cookieIdent = "c"
+	declareCookieVar, err := formVariableDeclaration(file, arg, param.Type())
+	if err != nil {
+		return nil, err
+	}
+	statements = append(statements, declareCookieVar)
+
+	return appendParseStructFieldsStatements(statements, t, file, resultType, arg, param, structFieldValues{
+		tag: CookieNameStructTag,
+		value: func(string) ast.Expr {
+			return &ast.SelectorExpr{X: ast.NewIdent(cookieIdent), Sel: ast.NewIdent("Value")}
+		},
+		ifPresent: func(name string, body []ast.Stmt) ast.Stmt {
+			return &ast.IfStmt{
+				Init: &ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent(cookieIdent), ast.NewIdent(errIdent)},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Cookie")}, Args: []ast.Expr{source.String(name)}}},
+				},
+				Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.EQL, Y: source.Nil()},
+				Body: &ast.BlockStmt{List: body},
+			}
+		},
+	}, aggregateValidationErrors, validationBlock, templateDataTypeIdent, templatesVariableIdent)
+}
+
+// appendDecodeJSONBodyStatements decodes an application/json request body (limited to maxBytes) into the body argument.
+// The handler responds with 415 when the request has another content type and 400 when decoding fails.
+func appendDecodeJSONBodyStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, arg *ast.Ident, param types.Object, maxBytes int64, templateDataTypeIdent, templatesVariableIdent string) ([]ast.Stmt, error) {
+	const (
+		mediaTypeIdent   = "mediaType"
+		contentTypeError = "expected Content-Type " + jsonContentType
+	)
+	contentTypeErrBlock, err := errorResultBlock(file, t, resultType, http.StatusUnsupportedMediaType, templateDataTypeIdent, templatesVariableIdent, file.Call("", "errors", "New", []ast.Expr{source.String(contentTypeError)}))
+	if err != nil {
+		return nil, err
+	}
+	decodeErrBlock, err := errorResultBlock(file, t, resultType, http.StatusBadRequest, templateDataTypeIdent, templatesVariableIdent, ast.NewIdent(errIdent))
+	if err != nil {
+		return nil, err
+	}
+	// decode into a new value for pointer types so the argument is never nil
+	var (
+		declareBodyVar ast.Stmt
+		decodeTarget   ast.Expr = &ast.UnaryExpr{Op: token.AND, X: ast.NewIdent(arg.Name)}
+	)
+	if ptr, ok := param.Type().(*types.Pointer); ok {
+		elemType, err := file.TypeASTExpression(ptr.Elem())
+		if err != nil {
+			return nil, err
+		}
+		declareBodyVar = &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(arg.Name)},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.CallExpr{Fun: ast.NewIdent("new"), Args: []ast.Expr{elemType}}},
+		}
+		decodeTarget = ast.NewIdent(arg.Name)
+	} else {
+		declareBodyVar, err = formVariableDeclaration(file, arg, param.Type())
+		if err != nil {
+			return nil, err
+		}
+	}
+	statements = append(statements,
+		&ast.IfStmt{
+			Init: &ast.AssignStmt{
+				Lhs: []ast.Expr{ast.NewIdent(mediaTypeIdent), ast.NewIdent("_"), ast.NewIdent("_")},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{file.Call("", "mime", "ParseMediaType", []ast.Expr{&ast.CallExpr{
+					Fun:  &ast.SelectorExpr{X: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Header")}, Sel: ast.NewIdent("Get")},
+					Args: []ast.Expr{source.String("Content-Type")},
+				}})},
+			},
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(mediaTypeIdent), Op: token.NEQ, Y: source.String(jsonContentType)},
+			Body: contentTypeErrBlock,
+		},
+		declareBodyVar,
+		&ast.IfStmt{
+			Init: singleAssignment(token.DEFINE, ast.NewIdent(errIdent))(&ast.CallExpr{
+				Fun: &ast.SelectorExpr{
+					X: file.Call("", "encoding/json", "NewDecoder", []ast.Expr{file.Call("", "net/http", "MaxBytesReader", []ast.Expr{
+						ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse),
+						&ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Body")},
+						byteSizeExpr(maxBytes),
+					})}),
+					Sel: ast.NewIdent("Decode"),
+				},
+				Args: []ast.Expr{decodeTarget},
+			}),
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: source.Nil()},
+			Body: decodeErrBlock,
+		},
+	)
+	if validate, ok := validateMethodCall(arg, param.Type()); ok {
+		validateErrBlock, err := errorResultBlock(file, t, resultType, http.StatusBadRequest, templateDataTypeIdent, templatesVariableIdent, ast.NewIdent(errIdent))
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, &ast.IfStmt{
+			Init: singleAssignment(token.DEFINE, ast.NewIdent(errIdent))(validate),
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: source.Nil()},
+			Body: validateErrBlock,
+		})
+	}
+	return statements, nil
+}
+
+// structFieldValues configures where the generated code reads the string values for each field of a struct argument.
+type structFieldValues struct {
+	// tag is the struct tag key used to override the name used to look up a field value.
+	tag string
+	// value returns an expression evaluating to the string value for the field name.
+	value func(name string) ast.Expr
+	// values returns an expression evaluating to all the string values ([]string) for the field name.
+	// When values is nil, slice fields are not supported.
+	values func(name string) ast.Expr
+	// files returns an expression evaluating to the uploaded files ([]*multipart.FileHeader) for the field name.
+	// When files is nil, file fields are not supported.
+	files func(name string) ast.Expr
+	// missing returns an expression that is true when no value was sent for the field name.
+	// When missing is nil, required inputs are not supported.
+	missing func(name string) ast.Expr
+	// ifPresent optionally wraps the parse statements for a field so they only run when a value for name was sent.
+	ifPresent func(name string, body []ast.Stmt) ast.Stmt
+}
+
+func appendParseStructFieldsStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, arg *ast.Ident, param types.Object, src structFieldValues, aggregateValidationErrors bool, validationBlock source.ValidationErrorBlock, templateDataTypeIdent, templatesVariableIdent string) ([]ast.Stmt, error) {
+	form, ok := param.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil, fmt.Errorf("expected %s parameter type to be a struct", arg.Name)
+	}
+	var err error
+	if !aggregateValidationErrors {
+		statements, err = appendParseNestedStructFieldsStatements(statements, t, file, resultType, arg.Name, ast.NewIdent(arg.Name), "", form, src, "", validationBlock, templateDataTypeIdent, templatesVariableIdent)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		validationErrorsIdent := arg.Name + "Errors"
+		statements = append(statements, singleAssignment(token.DEFINE, ast.NewIdent(validationErrorsIdent))(&ast.CallExpr{
+			Fun:  ast.NewIdent("make"),
+			Args: []ast.Expr{ast.NewIdent(validationErrorsTypeIdent(templateDataTypeIdent))},
+		}))
+		statements, err = appendParseNestedStructFieldsStatements(statements, t, file, resultType, arg.Name, ast.NewIdent(arg.Name), "", form, src, validationErrorsIdent, validationBlock, templateDataTypeIdent, templatesVariableIdent)
+		if err != nil {
+			return nil, err
+		}
+		errBlock, err := errorResultBlock(file, t, resultType, http.StatusBadRequest, templateDataTypeIdent, templatesVariableIdent, ast.NewIdent(validationErrorsIdent))
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{ast.NewIdent(validationErrorsIdent)}}, Op: token.GTR, Y: source.Int(0)},
+			Body: errBlock,
+		})
+	}
+	if validate, ok := validateMethodCall(arg, param.Type()); ok {
+		errBlock, err := errorResultBlock(file, t, resultType, http.StatusBadRequest, templateDataTypeIdent, templatesVariableIdent, ast.NewIdent(errIdent))
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, &ast.IfStmt{
+			Init: singleAssignment(token.DEFINE, ast.NewIdent(errIdent))(validate),
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: source.Nil()},
+			Body: errBlock,
+		})
+	}
+	return statements, nil
+}
+
+// validateMethodCall returns a call to the Validate method of the struct argument
+// when it (or its pointer) has a method with the signature Validate() error or Validate(context.Context) error.
+func validateMethodCall(arg *ast.Ident, tp types.Type) (*ast.CallExpr, bool) {
+	ptr, ok := tp.(*types.Pointer)
+	if !ok {
+		ptr = types.NewPointer(tp)
+	}
+	var pkg *types.Package
+	if named, ok := ptr.Elem().(*types.Named); ok {
+		pkg = named.Obj().Pkg()
+	}
+	obj, _, _ := types.LookupFieldOrMethod(ptr, false, pkg, "Validate")
+	method, ok := obj.(*types.Func)
+	if !ok {
+		return nil, false
+	}
+	sig := method.Type().(*types.Signature)
+	if sig.Results().Len() != 1 || !types.Identical(sig.Results().At(0).Type(), types.Universe.Lookup("error").Type()) {
+		return nil, false
+	}
+	call := &ast.CallExpr{Fun: &ast.SelectorExpr{X: arg, Sel: ast.NewIdent("Validate")}}
+	switch {
+	case sig.Params().Len() == 0:
+	case sig.Params().Len() == 1 && source.IsNamed(sig.Params().At(0).Type(), "context", "Context"):
+		call.Args = []ast.Expr{&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent(httpRequestContextMethod)}}}
+	default:
+		return nil, false
+	}
+	return call, true
+}
+
+// appendParseNestedStructFieldsStatements sets the fields of structType on target.
+// Nested struct fields are parsed from dotted names (for example "shipping.street") and the fields of
+// embedded structs are promoted so they do not get a name prefix.
+// When validationErrorsIdent is set, failures are added to the ValidationErrors variable with that name
+// and the remaining fields are still parsed.
+func appendParseNestedStructFieldsStatements(statements []ast.Stmt, t *Template, file *source.File, resultType types.Type, argName string, target ast.Expr, namePrefix string, structType *types.Struct, src structFieldValues, validationErrorsIdent string, validationBlock source.ValidationErrorBlock, templateDataTypeIdent, templatesVariableIdent string) ([]ast.Stmt, error) {
+	const parsedVariableName = "value"
+
+	for i := 0; i < structType.NumFields(); i++ {
+		field, tags := structType.Field(i), reflect.StructTag(structType.Tag(i))
+		if outPkg := file.OutputPackage(); !field.Exported() && outPkg != nil && field.Pkg() != nil && field.Pkg().Path() != outPkg.PkgPath {
+			continue
+		}
+		fieldName, hasNameTag := tags.Lookup(src.tag)
+		if !hasNameTag {
+			fieldName = field.Name()
+		}
+		inputName := namePrefix + fieldName
+		fieldExpr := &ast.SelectorExpr{X: target, Sel: ast.NewIdent(field.Name())}
+		fieldPath := strings.TrimPrefix(source.Format(fieldExpr), argName+".")
+
+		if nested, ok := nestedStructType(file, field.Type()); ok {
+			prefix := namePrefix
+			if !field.Embedded() || hasNameTag {
+				prefix = inputName + "."
+			}
+			var err error
+			statements, err = appendParseNestedStructFieldsStatements(statements, t, file, resultType, argName, fieldExpr, prefix, nested, src, validationErrorsIdent, validationBlock, templateDataTypeIdent, templatesVariableIdent)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fragment := structFieldTemplateFragment(t, tags)
+		input := fragment.QuerySelector(fmt.Sprintf("[name=%q]", inputName))
+		required := input != nil && input.HasAttribute("required")
+		fieldValidationBlock, failureBlock := validationBlock, validationBlock
+		errBlock := func() (*ast.BlockStmt, error) {
+			return errorResultBlock(file, t, resultType, http.StatusBadRequest, templateDataTypeIdent, templatesVariableIdent, ast.NewIdent(errIdent))
+		}
+		if validationErrorsIdent != "" {
+			addValidationError := func(message ast.Expr) ast.Stmt {
+				return &ast.ExprStmt{X: &ast.CallExpr{
+					Fun:  &ast.SelectorExpr{X: ast.NewIdent(validationErrorsIdent), Sel: ast.NewIdent("Add")},
+					Args: []ast.Expr{source.String(inputName), message},
+				}}
+			}
+			fieldValidationBlock = func(message string) *ast.BlockStmt {
+				return &ast.BlockStmt{List: []ast.Stmt{addValidationError(source.String(message))}}
+			}
+			failureBlock = func(message string) *ast.BlockStmt {
+				return &ast.BlockStmt{List: []ast.Stmt{addValidationError(source.String(message)), &ast.ReturnStmt{}}}
+			}
+			errBlock = func() (*ast.BlockStmt, error) {
+				return &ast.BlockStmt{List: []ast.Stmt{
+					addValidationError(&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(errIdent), Sel: ast.NewIdent("Error")}}),
+					&ast.ReturnStmt{},
+				}}, nil
+			}
+		}
+		requiredCheck := func(missing ast.Expr) ast.Stmt {
+			return &ast.IfStmt{Cond: missing, Body: failureBlock(fmt.Sprintf("%s is required", inputName))}
 		}
-		var fieldTemplate *template.Template
-		if name, found := tags.Lookup(InputAttributeTemplateStructTag); found {
-			fieldTemplate = t.template.Lookup(name)
+		lenIsZero := func(exp ast.Expr) ast.Expr {
+			return &ast.BinaryExpr{X: &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{exp}}, Op: token.EQL, Y: source.Int(0)}
 		}
-		var templateNodes []*html.Node
-		if fieldTemplate != nil {
-			templateNodes, _ = html.ParseFragment(strings.NewReader(fieldTemplate.Tree.Root.String()), &html.Node{
-				Type:     html.ElementNode,
-				DataAtom: atom.Body,
-				Data:     atom.Body.String(),
-			})
+
+		if isSlice, ok := multipartFileHeaderType(field.Type()); ok {
+			if src.files == nil {
+				return nil, fmt.Errorf("%s field %s: file fields are only supported for multipart form parameters", argName, fieldPath)
+			}
+			const filesIdent = "files"
+			fileStatements := []ast.Stmt{singleAssignment(token.DEFINE, ast.NewIdent(filesIdent))(src.files(inputName))}
+			if required {
+				fileStatements = append(fileStatements, requiredCheck(lenIsZero(ast.NewIdent(filesIdent))))
+			}
+			validations, err, ok := source.GenerateValidations(file, ast.NewIdent(filesIdent), field.Type(), fmt.Sprintf("[name=%q]", inputName), inputName, httpResponseField(file).Names[0].Name, fragment, fieldValidationBlock)
+			if ok && err != nil {
+				return nil, fmt.Errorf("%s field %s: input %q: %w", argName, fieldPath, inputName, err)
+			}
+			fileStatements = append(fileStatements, validations...)
+			if isSlice {
+				fileStatements = append(fileStatements, &ast.AssignStmt{Lhs: []ast.Expr{fieldExpr}, Tok: token.ASSIGN, Rhs: []ast.Expr{ast.NewIdent(filesIdent)}})
+			} else {
+				fileStatements = append(fileStatements, &ast.IfStmt{
+					Cond: &ast.BinaryExpr{X: &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{ast.NewIdent(filesIdent)}}, Op: token.GTR, Y: source.Int(0)},
+					Body: &ast.BlockStmt{List: []ast.Stmt{
+						&ast.AssignStmt{Lhs: []ast.Expr{fieldExpr}, Tok: token.ASSIGN, Rhs: []ast.Expr{&ast.IndexExpr{X: ast.NewIdent(filesIdent), Index: source.Int(0)}}},
+					}},
+				})
+			}
+			if validationErrorsIdent != "" {
+				statements = append(statements, fieldParseStatements(fileStatements)...)
+			} else {
+				statements = append(statements, &ast.BlockStmt{List: fileStatements})
+			}
+			continue
 		}
 		var (
 			parseResult func(expr ast.Expr) ast.Stmt
@@ -1011,64 +2197,261 @@ func appendParseFormToStructStatements(statements []ast.Stmt, t *Template, file
 		)
 		switch ft := field.Type().(type) {
 		case *types.Slice:
+			if src.values == nil {
+				return nil, fmt.Errorf("%s field %s: slice fields are not supported", argName, fieldPath)
+			}
 			parseResult = func(expr ast.Expr) ast.Stmt {
 				return &ast.AssignStmt{
-					Lhs: []ast.Expr{&ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierForm), Sel: ast.NewIdent(field.Name())}},
+					Lhs: []ast.Expr{fieldExpr},
 					Tok: token.ASSIGN,
 					Rhs: []ast.Expr{&ast.CallExpr{
 						Fun:  ast.NewIdent("append"),
-						Args: []ast.Expr{&ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierForm), Sel: ast.NewIdent(field.Name())}, expr},
+						Args: []ast.Expr{fieldExpr, expr},
 					}},
 				}
 			}
 			str = ast.NewIdent("val")
 			elemType = ft.Elem()
-			validations, err, ok := source.GenerateValidations(file, ast.NewIdent(parsedVariableName), elemType, fmt.Sprintf("[name=%q]", inputName), inputName, httpResponseField(file).Names[0].Name, dom.NewDocumentFragment(templateNodes), validationBlock)
+			validations, err, ok := source.GenerateValidations(file, ast.NewIdent(parsedVariableName), elemType, fmt.Sprintf("[name=%q]", inputName), inputName, httpResponseField(file).Names[0].Name, fragment, fieldValidationBlock)
 			if ok && err != nil {
+				return nil, fmt.Errorf("%s field %s: input %q: %w", argName, fieldPath, inputName, err)
+			}
+			parseErrBlock, err := errBlock()
+			if err != nil {
 				return nil, err
 			}
-			parseStatements, err := generateParseValueFromStringStatements(file, t, parsedVariableName, resultType, str, elemType, validations, parseResult, templateDataTypeIdent, templatesVariableIdent)
+			parseStatements, err := generateParseFieldValueStatements(file, templateDataTypeIdent, parsedVariableName, str, elemType, input, validations, parseErrBlock, parseResult)
 			if err != nil {
-				return nil, fmt.Errorf("failed to generate parse statements for form field %s: %w", field.Name(), err)
+				return nil, fmt.Errorf("failed to generate parse statements for %s field %s: %w", argName, fieldPath, err)
 			}
-			statements = append(statements, &ast.RangeStmt{
+			var fieldStatements []ast.Stmt
+			if required {
+				fieldStatements = append(fieldStatements, requiredCheck(lenIsZero(src.values(inputName))))
+			}
+			fieldStatements = append(fieldStatements, &ast.RangeStmt{
 				Key:   ast.NewIdent("_"),
 				Value: ast.NewIdent("val"),
 				Tok:   token.DEFINE,
-				X:     &ast.IndexExpr{X: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Form")}, Index: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(inputName)}},
+				X:     src.values(inputName),
 				Body:  &ast.BlockStmt{List: parseStatements},
 			})
+			if validationErrorsIdent != "" {
+				fieldStatements = fieldParseStatements(fieldStatements)
+			}
+			statements = append(statements, fieldStatements...)
 		default:
+			if input != nil && strings.EqualFold(input.TagName(), atom.Select.String()) && input.HasAttribute("multiple") {
+				return nil, fmt.Errorf("%s field %s: <select multiple> requires a slice field", argName, fieldPath)
+			}
+			const optionalValueIdent = "str"
+			var assignTo ast.Expr = fieldExpr
+			str = src.value(inputName)
+			elemType = field.Type()
+			ptr, isPointer := elemType.(*types.Pointer)
+			if isPointer {
+				elemType = ptr.Elem()
+				assignTo = &ast.StarExpr{X: fieldExpr}
+				str = ast.NewIdent(optionalValueIdent)
+			}
 			parseResult = func(expr ast.Expr) ast.Stmt {
 				return &ast.AssignStmt{
-					Lhs: []ast.Expr{&ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierForm), Sel: ast.NewIdent(field.Name())}},
+					Lhs: []ast.Expr{assignTo},
 					Tok: token.ASSIGN,
 					Rhs: []ast.Expr{expr},
 				}
 			}
-			str = &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("FormValue")}, Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(inputName)}}}
-			elemType = field.Type()
-			validations, err, ok := source.GenerateValidations(file, ast.NewIdent(parsedVariableName), elemType, fmt.Sprintf("[name=%q]", inputName), inputName, httpResponseField(file).Names[0].Name, dom.NewDocumentFragment(templateNodes), validationBlock)
+			validations, err, ok := source.GenerateValidations(file, ast.NewIdent(parsedVariableName), elemType, fmt.Sprintf("[name=%q]", inputName), inputName, httpResponseField(file).Names[0].Name, fragment, fieldValidationBlock)
 			if ok && err != nil {
+				return nil, fmt.Errorf("%s field %s: input %q: %w", argName, fieldPath, inputName, err)
+			}
+			parseErrBlock, err := errBlock()
+			if err != nil {
 				return nil, err
 			}
-			parseStatements, err := generateParseValueFromStringStatements(file, t, parsedVariableName, resultType, str, elemType, validations, parseResult, templateDataTypeIdent, templatesVariableIdent)
+			parseStatements, err := generateParseFieldValueStatements(file, templateDataTypeIdent, parsedVariableName, str, elemType, input, validations, parseErrBlock, parseResult)
 			if err != nil {
-				return nil, fmt.Errorf("failed to generate parse statements for form field %s: %w", field.Name(), err)
+				return nil, fmt.Errorf("failed to generate parse statements for %s field %s: %w", argName, fieldPath, err)
 			}
-			if len(parseStatements) > 1 {
-				statements = append(statements, &ast.BlockStmt{
+			if isPointer {
+				elemTypeExpr, err := file.TypeASTExpression(elemType)
+				if err != nil {
+					return nil, err
+				}
+				parseStatements = []ast.Stmt{&ast.IfStmt{
+					Init: singleAssignment(token.DEFINE, ast.NewIdent(optionalValueIdent))(src.value(inputName)),
+					Cond: &ast.BinaryExpr{X: ast.NewIdent(optionalValueIdent), Op: token.NEQ, Y: source.String("")},
+					Body: &ast.BlockStmt{List: append([]ast.Stmt{
+						singleAssignment(token.ASSIGN, fieldExpr)(&ast.CallExpr{Fun: ast.NewIdent("new"), Args: []ast.Expr{elemTypeExpr}}),
+					}, parseStatements...)},
+				}}
+			}
+			var fieldStatements []ast.Stmt
+			if required {
+				if src.missing == nil {
+					return nil, fmt.Errorf("%s field %s: required inputs are not supported for %s fields", argName, fieldPath, src.tag)
+				}
+				fieldStatements = append(fieldStatements, requiredCheck(src.missing(inputName)))
+			}
+			if src.ifPresent != nil {
+				fieldStatements = append(fieldStatements, src.ifPresent(inputName, parseStatements))
+			} else if validationErrorsIdent == "" && len(parseStatements) > 1 {
+				fieldStatements = append(fieldStatements, &ast.BlockStmt{
 					List: parseStatements,
 				})
 			} else {
-				statements = append(statements, parseStatements...)
+				fieldStatements = append(fieldStatements, parseStatements...)
 			}
+			if validationErrorsIdent != "" {
+				fieldStatements = fieldParseStatements(fieldStatements)
+			}
+			statements = append(statements, fieldStatements...)
 		}
 	}
 
 	return statements, nil
 }
 
+// fieldParseStatements puts the statements parsing a field in a block. The statements return after adding
+// a validation error; each return is replaced so it skips the rest of the field and not the remaining fields.
+func fieldParseStatements(list []ast.Stmt) []ast.Stmt {
+	list = skipRestOfFieldStatements(list, false)
+	if len(list) > 1 {
+		return []ast.Stmt{&ast.BlockStmt{List: list}}
+	}
+	return list
+}
+
+// skipRestOfFieldStatements moves the statements after an if statement ending with a return into its else block.
+// In a loop body, the return is replaced with a break.
+func skipRestOfFieldStatements(list []ast.Stmt, inLoop bool) []ast.Stmt {
+	for i, stmt := range list {
+		if !hasReturnStatement(stmt) {
+			continue
+		}
+		rest := list[i+1:]
+		switch s := stmt.(type) {
+		case *ast.IfStmt:
+			n := len(s.Body.List)
+			if _, ok := s.Body.List[n-1].(*ast.ReturnStmt); !ok || s.Else != nil {
+				s.Body.List = skipRestOfFieldStatements(s.Body.List, inLoop)
+				continue
+			}
+			if inLoop {
+				s.Body.List[n-1] = &ast.BranchStmt{Tok: token.BREAK}
+				continue
+			}
+			s.Body.List = s.Body.List[:n-1]
+			if rest = skipRestOfFieldStatements(rest, inLoop); len(rest) > 0 {
+				s.Else = &ast.BlockStmt{List: rest}
+			}
+			return list[:i+1]
+		case *ast.BlockStmt:
+			s.List = skipRestOfFieldStatements(append(s.List, rest...), inLoop)
+			return list[:i+1]
+		case *ast.RangeStmt:
+			s.Body.List = skipRestOfFieldStatements(s.Body.List, true)
+		}
+	}
+	return list
+}
+
+func hasReturnStatement(stmt ast.Stmt) bool {
+	found := false
+	ast.Inspect(stmt, func(node ast.Node) bool {
+		switch node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// structFieldTemplateFragment parses the template named by the field's template tag
+// so the input for the field can be found. The fragment is empty when the tag is not set.
+func structFieldTemplateFragment(t *Template, tags reflect.StructTag) *dom.DocumentFragment {
+	var fieldTemplate *template.Template
+	if name, found := tags.Lookup(InputAttributeTemplateStructTag); found && t.template != nil {
+		fieldTemplate = t.template.Lookup(name)
+	}
+	var templateNodes []*html.Node
+	if fieldTemplate != nil {
+		templateNodes, _ = html.ParseFragment(strings.NewReader(fieldTemplate.Tree.Root.String()), &html.Node{
+			Type:     html.ElementNode,
+			DataAtom: atom.Body,
+			Data:     atom.Body.String(),
+		})
+	}
+	return dom.NewDocumentFragment(templateNodes)
+}
+
+// nestedStructType returns the struct type for fields whose values are parsed field by field.
+// Structs implementing encoding.TextUnmarshaler (such as time.Time) are parsed from a single value instead.
+// Only structs declared in the output package's module are parsed field by field;
+// structs from other modules (such as url.URL) are unsupported field types.
+func nestedStructType(file *source.File, tp types.Type) (*types.Struct, bool) {
+	st, ok := tp.Underlying().(*types.Struct)
+	if !ok || implementsTextUnmarshaler(file, tp) || !declaredInOutputModule(file, tp) {
+		return nil, false
+	}
+	return st, true
+}
+
+func declaredInOutputModule(file *source.File, tp types.Type) bool {
+	named, ok := types.Unalias(tp).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return true
+	}
+	pkgPath, outPkg := named.Obj().Pkg().Path(), file.OutputPackage()
+	if outPkg.Module == nil {
+		return pkgPath == outPkg.PkgPath
+	}
+	return pkgPath == outPkg.Module.Path || strings.HasPrefix(pkgPath, outPkg.Module.Path+"/")
+}
+
+// multipartFileHeaderType reports whether tp is *multipart.FileHeader or []*multipart.FileHeader.
+func multipartFileHeaderType(tp types.Type) (isSlice, ok bool) {
+	if slice, isSliceType := tp.(*types.Slice); isSliceType {
+		tp, isSlice = slice.Elem(), true
+	}
+	ptr, isPointer := tp.(*types.Pointer)
+	return isSlice, isPointer && source.IsNamed(ptr.Elem(), "mime/multipart", "FileHeader")
+}
+
+func hasMultipartFileFields(file *source.File, structType *types.Struct) bool {
+	for i := 0; i < structType.NumFields(); i++ {
+		tp := structType.Field(i).Type()
+		if _, ok := multipartFileHeaderType(tp); ok {
+			return true
+		}
+		if nested, ok := nestedStructType(file, tp); ok && hasMultipartFileFields(file, nested) {
+			return true
+		}
+	}
+	return false
+}
+
+// byteSizeExpr formats whole mebibyte sizes like 32 << 20.
+func byteSizeExpr(n int64) ast.Expr {
+	for _, shift := range []int{20, 10} {
+		if n > 0 && n%(1<<shift) == 0 {
+			return &ast.BinaryExpr{X: &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(n>>shift, 10)}, Op: token.SHL, Y: source.Int(shift)}
+		}
+	}
+	return &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(n, 10)}
+}
+
+func implementsTextUnmarshaler(file *source.File, tp types.Type) bool {
+	encPkg, ok := file.Types("encoding")
+	if !ok {
+		return false
+	}
+	textUnmarshaler := encPkg.Scope().Lookup("TextUnmarshaler").Type().Underlying().(*types.Interface)
+	return types.Implements(types.NewPointer(tp), textUnmarshaler)
+}
+
 func formVariableDeclaration(file *source.File, arg *ast.Ident, tp types.Type) (*ast.DeclStmt, error) {
 	typeExp, err := file.TypeASTExpression(tp)
 	if err != nil {
@@ -1111,20 +2494,34 @@ func formVariableAssignment(file *source.File, arg *ast.Ident, tp types.Type) (*
 	}, nil
 }
 
-func httpServeMuxField(file *source.File) *ast.Field {
+func httpServeMuxField(file *source.File, muxInterfaceTypeName string) *ast.Field {
+	if muxInterfaceTypeName != "" {
+		return &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(muxParamName)},
+			Type:  ast.NewIdent(muxInterfaceTypeName),
+		}
+	}
 	return &ast.Field{
 		Names: []*ast.Ident{ast.NewIdent(muxParamName)},
 		Type:  &ast.StarExpr{X: &ast.SelectorExpr{X: ast.NewIdent(file.AddNetHTTP()), Sel: ast.NewIdent("ServeMux")}},
 	}
 }
 
+// muxInterfaceType has the Handle method of *http.ServeMux used to register handlers.
+func muxInterfaceType(file *source.File) *ast.InterfaceType {
+	return &ast.InterfaceType{Methods: &ast.FieldList{List: []*ast.Field{{
+		Names: []*ast.Ident{ast.NewIdent(httpHandleIdent)},
+		Type: &ast.FuncType{Params: &ast.FieldList{List: []*ast.Field{
+			{Names: []*ast.Ident{ast.NewIdent("pattern")}, Type: ast.NewIdent("string")},
+			{Names: []*ast.Ident{ast.NewIdent("handler")}, Type: &ast.SelectorExpr{X: ast.NewIdent(file.AddNetHTTP()), Sel: ast.NewIdent("Handler")}},
+		}}},
+	}}}}
+}
+
 func errorResultBlock(file *source.File, t *Template, resultType types.Type, fallbackStatusCode int, templateDataTypeIdent, templatesVariableIdent string, errExp ast.Expr) (*ast.BlockStmt, error) {
 	const (
-		statusCodeIdent = "sc"
-		errIdent        = "err"
 		resultDataIdent = "rd"
 		zeroValueIdent  = "zv"
-		bufIdent        = "buf"
 	)
 
 	typeExpr, err := file.TypeASTExpression(resultType)
@@ -1132,16 +2529,6 @@ func errorResultBlock(file *source.File, t *Template, resultType types.Type, fal
 		return nil, err
 	}
 
-	execTemplate := checkExecuteTemplateError(file)
-	execTemplate.Init = &ast.AssignStmt{
-		Lhs: []ast.Expr{ast.NewIdent(errIdent)},
-		Tok: token.DEFINE,
-		Rhs: []ast.Expr{&ast.CallExpr{
-			Fun:  &ast.SelectorExpr{X: ast.NewIdent(templatesVariableIdent), Sel: ast.NewIdent("ExecuteTemplate")},
-			Args: []ast.Expr{ast.NewIdent(bufIdent), &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(t.name)}, ast.NewIdent(resultDataIdent)},
-		}},
-	}
-
 	list := []ast.Stmt{
 		&ast.DeclStmt{Decl: &ast.GenDecl{
 			Tok: token.VAR,
@@ -1155,37 +2542,17 @@ func errorResultBlock(file *source.File, t *Template, resultType types.Type, fal
 		&ast.AssignStmt{
 			Lhs: []ast.Expr{ast.NewIdent(resultDataIdent)},
 			Tok: token.DEFINE,
-			Rhs: []ast.Expr{&ast.CallExpr{
-				Fun: ast.NewIdent(newResponseDataFuncIdent(templateDataTypeIdent)),
-				Args: []ast.Expr{
-					ast.NewIdent(receiverIdent),
-					ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse),
-					ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest),
-					ast.NewIdent(zeroValueIdent),
-					source.Bool(false),
-					errExp,
-				},
-			}},
-		},
-		&ast.AssignStmt{
-			Lhs: []ast.Expr{ast.NewIdent(bufIdent)},
-			Tok: token.DEFINE,
-			Rhs: []ast.Expr{file.BytesNewBuffer(source.Nil())},
+			Rhs: []ast.Expr{newTemplateDataCall(t, templateDataTypeIdent, ast.NewIdent(zeroValueIdent), source.Bool(false), errExp)},
 		},
-		execTemplate,
 	}
-
-	list = append(list, writeStatusAndHeaders(file, t, resultType, fallbackStatusCode, statusCodeIdent, bufIdent, resultDataIdent)...)
+	list = append(list, negotiateJSONStatements(file, t, resultType, fallbackStatusCode, resultDataIdent)...)
+	list = append(list, callRender(t, resultDataIdent, resultStatusCodeExpression(file, resultType, fallbackStatusCode)))
 
 	block := &ast.BlockStmt{List: append(list, &ast.ReturnStmt{})}
 	return block, nil
 }
 
-func generateParseValueFromStringStatements(file *source.File, t *Template, tmp string, resultType types.Type, str ast.Expr, valueType types.Type, validations []ast.Stmt, assignment func(ast.Expr) ast.Stmt, templateDataTypeIdent, templatesVariableIdent string) ([]ast.Stmt, error) {
-	errBlock, err := errorResultBlock(file, t, resultType, http.StatusBadRequest, templateDataTypeIdent, templatesVariableIdent, ast.NewIdent(errIdent))
-	if err != nil {
-		return nil, err
-	}
+func generateParseValueFromStringStatements(file *source.File, tmp string, str ast.Expr, valueType types.Type, validations []ast.Stmt, errBlock *ast.BlockStmt, assignment func(ast.Expr) ast.Stmt) ([]ast.Stmt, error) {
 	switch tp := valueType.(type) {
 	case *types.Basic:
 		convert := func(exp ast.Expr) ast.Stmt {
@@ -1219,6 +2586,10 @@ func generateParseValueFromStringStatements(file *source.File, t *Template, tmp
 			return parseBlock(tmp, file.StrconvParseUint32Call(str), validations, errBlock, convert), nil
 		case "uint64":
 			return parseBlock(tmp, file.StrconvParseUint64Call(str), validations, errBlock, assignment), nil
+		case "float32":
+			return parseBlock(tmp, file.StrconvParseFloat32Call(str), validations, errBlock, convert), nil
+		case "float64":
+			return parseBlock(tmp, file.StrconvParseFloat64Call(str), validations, errBlock, assignment), nil
 		case "string":
 			if len(validations) == 0 {
 				assign := assignment(str)
@@ -1233,54 +2604,89 @@ func generateParseValueFromStringStatements(file *source.File, t *Template, tmp
 			return statements, nil
 		}
 	case *types.Named:
-		if encPkg, ok := file.Types("encoding"); ok {
-			if textUnmarshaler := encPkg.Scope().Lookup("TextUnmarshaler").Type().Underlying().(*types.Interface); types.Implements(types.NewPointer(tp), textUnmarshaler) {
-				tp, _ := file.TypeASTExpression(valueType)
-				return []ast.Stmt{
-					&ast.DeclStmt{
-						Decl: &ast.GenDecl{
-							Tok: token.VAR,
-							Specs: []ast.Spec{
-								&ast.ValueSpec{
-									Names: []*ast.Ident{ast.NewIdent(tmp)},
-									Type:  tp,
-								},
+		if source.IsNamed(tp, "time", "Duration") {
+			return parseBlock(tmp, file.TimeParseDurationCall(str), validations, errBlock, assignment), nil
+		}
+		if implementsTextUnmarshaler(file, tp) {
+			tp, _ := file.TypeASTExpression(valueType)
+			return []ast.Stmt{
+				&ast.DeclStmt{
+					Decl: &ast.GenDecl{
+						Tok: token.VAR,
+						Specs: []ast.Spec{
+							&ast.ValueSpec{
+								Names: []*ast.Ident{ast.NewIdent(tmp)},
+								Type:  tp,
 							},
 						},
 					},
-					&ast.IfStmt{
-						Init: &ast.AssignStmt{
-							Lhs: []ast.Expr{ast.NewIdent(errIdent)},
-							Tok: token.DEFINE,
-							Rhs: []ast.Expr{&ast.CallExpr{
-								Fun: &ast.SelectorExpr{
-									X:   ast.NewIdent(tmp),
-									Sel: ast.NewIdent("UnmarshalText"),
+				},
+				&ast.IfStmt{
+					Init: &ast.AssignStmt{
+						Lhs: []ast.Expr{ast.NewIdent(errIdent)},
+						Tok: token.DEFINE,
+						Rhs: []ast.Expr{&ast.CallExpr{
+							Fun: &ast.SelectorExpr{
+								X:   ast.NewIdent(tmp),
+								Sel: ast.NewIdent("UnmarshalText"),
+							},
+							Args: []ast.Expr{&ast.CallExpr{
+								Fun: &ast.ArrayType{
+									Elt: ast.NewIdent("byte"),
 								},
-								Args: []ast.Expr{&ast.CallExpr{
-									Fun: &ast.ArrayType{
-										Elt: ast.NewIdent("byte"),
-									},
-									Args: []ast.Expr{str},
-								}},
+								Args: []ast.Expr{str},
 							}},
-						},
-						Cond: &ast.BinaryExpr{
-							X:  ast.NewIdent(errIdent),
-							Op: token.NEQ,
-							Y:  ast.NewIdent("nil"),
-						},
-						Body: errBlock,
+						}},
 					},
-					assignment(ast.NewIdent(tmp)),
-				}, nil
-			}
+					Cond: &ast.BinaryExpr{
+						X:  ast.NewIdent(errIdent),
+						Op: token.NEQ,
+						Y:  ast.NewIdent("nil"),
+					},
+					Body: errBlock,
+				},
+				assignment(ast.NewIdent(tmp)),
+			}, nil
 		}
 	}
 	tp, _ := file.TypeASTExpression(valueType)
 	return nil, fmt.Errorf("unsupported type: %s", source.Format(tp))
 }
 
+// generateParseFieldValueStatements parses bool values for checkbox inputs as present or absent and time.Time values
+// using the format of the date or time input element with the field's name; other types are parsed with
+// generateParseValueFromStringStatements.
+func generateParseFieldValueStatements(file *source.File, templateDataTypeIdent, tmp string, str ast.Expr, valueType types.Type, input spec.Element, validations []ast.Stmt, errBlock *ast.BlockStmt, assignment func(ast.Expr) ast.Stmt) ([]ast.Stmt, error) {
+	if input != nil && strings.EqualFold(input.GetAttribute("type"), "checkbox") && types.Identical(valueType, types.Typ[types.Bool]) {
+		return []ast.Stmt{assignment(&ast.BinaryExpr{X: str, Op: token.NEQ, Y: source.String("")})}, nil
+	}
+	if input == nil || !source.IsNamed(valueType, "time", "Time") {
+		return generateParseValueFromStringStatements(file, tmp, str, valueType, validations, errBlock, assignment)
+	}
+	if input.GetAttribute("type") == "week" {
+		return parseBlock(tmp, &ast.CallExpr{Fun: ast.NewIdent(parseWeekFuncIdent(templateDataTypeIdent)), Args: []ast.Expr{str}}, validations, errBlock, assignment), nil
+	}
+	layout, ok := source.TimeInputLayout(input)
+	if !ok {
+		return generateParseValueFromStringStatements(file, tmp, str, valueType, validations, errBlock, assignment)
+	}
+	return parseBlock(tmp, file.TimeParseCall(layout, str), validations, errBlock, assignment), nil
+}
+
+// hasCall reports whether node has a call to the function named ident.
+func hasCall(node ast.Node, ident string) bool {
+	found := false
+	ast.Inspect(node, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok {
+			if fun, ok := call.Fun.(*ast.Ident); ok && fun.Name == ident {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
 func parseBlock(tmpIdent string, parseCall ast.Expr, validations []ast.Stmt, errBlock *ast.BlockStmt, handleResult func(out ast.Expr) ast.Stmt) []ast.Stmt {
 	const errIdent = "err"
 	callParse := &ast.AssignStmt{
@@ -1380,13 +2786,34 @@ func defaultTemplateNameScope(file *source.File, template *Template, argumentIde
 		}
 		t := pkg.Scope().Lookup("Context").Type()
 		return t, true
-	case TemplateNameScopeIdentifierForm:
+	case TemplateNameScopeIdentifierForm, TemplateNameScopeIdentifierQuery:
 		pkg, ok := file.Types("net/url")
 		if !ok {
 			return nil, false
 		}
 		t := pkg.Scope().Lookup("Values").Type()
 		return t, true
+	case TemplateNameScopeIdentifierHeader:
+		pkg, ok := file.Types("net/http")
+		if !ok {
+			return nil, false
+		}
+		t := pkg.Scope().Lookup("Header").Type()
+		return t, true
+	case TemplateNameScopeIdentifierCookie:
+		pkg, ok := file.Types("net/http")
+		if !ok {
+			return nil, false
+		}
+		t := types.NewSlice(types.NewPointer(pkg.Scope().Lookup("Cookie").Type()))
+		return t, true
+	case TemplateNameScopeIdentifierBody:
+		pkg, ok := file.Types("encoding/json")
+		if !ok {
+			return nil, false
+		}
+		t := pkg.Scope().Lookup("RawMessage").Type()
+		return t, true
 	default:
 		if slices.Contains(template.parsePathValueNames(), argumentIdentifier) {
 			return types.Universe.Lookup("string").Type(), true
@@ -1476,6 +2903,15 @@ func createMethodSignature(file *source.File, signatures map[string]*types.Signa
 	return types.NewSignatureType(types.NewVar(0, nil, "", receiver.Obj().Type()), nil, nil, types.NewTuple(params...), results, false), nil
 }
 
+func requestURLQueryCall() *ast.CallExpr {
+	return &ast.CallExpr{
+		Fun: &ast.SelectorExpr{
+			X:   &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("URL")},
+			Sel: ast.NewIdent("Query"),
+		},
+	}
+}
+
 func callParseForm() *ast.ExprStmt {
 	return &ast.ExprStmt{X: &ast.CallExpr{
 		Fun: &ast.SelectorExpr{
@@ -1529,67 +2965,249 @@ func singleAssignment(assignTok token.Token, result ast.Expr) func(exp ast.Expr)
 
 var statusCoder = statusCoderInterface()
 
-func writeStatusAndHeaders(file *source.File, _ *Template, resultType types.Type, fallbackStatusCode int, statusCode, bufIdent, resultDataIdent string) []ast.Stmt {
-	statusCodePriorityList := []ast.Expr{
+// statusCodeExpression is the status code set by the template, the result status code, or the fallback status code.
+func statusCodeExpression(file *source.File, resultType types.Type, fallbackStatusCode int, resultDataIdent string) *ast.CallExpr {
+	return file.Call("", "cmp", "Or", append([]ast.Expr{
 		&ast.SelectorExpr{X: ast.NewIdent(resultDataIdent), Sel: ast.NewIdent(templateDataFieldStatusCode)},
+	}, resultStatusCodes(file, resultType, fallbackStatusCode)...))
+}
+
+// resultStatusCodeExpression is the result status code or the fallback status code.
+func resultStatusCodeExpression(file *source.File, resultType types.Type, fallbackStatusCode int) ast.Expr {
+	list := resultStatusCodes(file, resultType, fallbackStatusCode)
+	if len(list) == 1 {
+		return list[0]
 	}
+	return file.Call("", "cmp", "Or", list)
+}
+
+func resultStatusCodes(file *source.File, resultType types.Type, fallbackStatusCode int) []ast.Expr {
+	var list []ast.Expr
 	if types.Implements(resultType, statusCoder) {
-		statusCodePriorityList = append(statusCodePriorityList, &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent("result"), Sel: ast.NewIdent("StatusCode")}})
+		list = append(list, &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent("result"), Sel: ast.NewIdent("StatusCode")}})
 	} else if obj, _, _ := types.LookupFieldOrMethod(resultType, true, file.OutputPackage().Types, "StatusCode"); obj != nil {
-		statusCodePriorityList = append(statusCodePriorityList, &ast.SelectorExpr{X: ast.NewIdent("result"), Sel: ast.NewIdent("StatusCode")})
+		list = append(list, &ast.SelectorExpr{X: ast.NewIdent("result"), Sel: ast.NewIdent("StatusCode")})
 	}
-	statusCodePriorityList = append(statusCodePriorityList, source.HTTPStatusCode(file, fallbackStatusCode))
-	list := []ast.Stmt{
+	return append(list, source.HTTPStatusCode(file, fallbackStatusCode))
+}
+
+// pooledBufferStatements get a buffer from the template buffer pool and defer returning it.
+func pooledBufferStatements(file *source.File, templateDataTypeIdent, bufIdent string) []ast.Stmt {
+	return []ast.Stmt{
 		&ast.AssignStmt{
-			Lhs: []ast.Expr{ast.NewIdent(statusCode)},
+			Lhs: []ast.Expr{ast.NewIdent(bufIdent)},
 			Tok: token.DEFINE,
-			Rhs: []ast.Expr{
-				file.Call("", "cmp", "Or", statusCodePriorityList),
-			},
+			Rhs: []ast.Expr{&ast.TypeAssertExpr{
+				X:    &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(bufferPoolVarIdent(templateDataTypeIdent)), Sel: ast.NewIdent("Get")}},
+				Type: &ast.StarExpr{X: &ast.SelectorExpr{X: ast.NewIdent(file.Import("", "bytes")), Sel: ast.NewIdent("Buffer")}},
+			}},
 		},
-		&ast.IfStmt{ // TODO: make this conditional on a redirect call in the template actions
-			Cond: &ast.BinaryExpr{
-				X: &ast.SelectorExpr{
-					X:   ast.NewIdent(resultDataIdent),
-					Sel: ast.NewIdent(TemplateDataFieldIdentifierRedirectURL),
-				},
-				Op: token.NEQ,
-				Y:  source.String(""),
-			},
-			Body: &ast.BlockStmt{
-				List: []ast.Stmt{
-					&ast.ExprStmt{
-						X: file.Call("", "net/http", "Redirect", []ast.Expr{
-							ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse),
-							ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest),
-							&ast.SelectorExpr{
-								X:   ast.NewIdent(resultDataIdent),
-								Sel: ast.NewIdent(TemplateDataFieldIdentifierRedirectURL),
-							},
-							ast.NewIdent(statusCode),
-						}),
+		&ast.DeferStmt{Call: &ast.CallExpr{Fun: ast.NewIdent(putBufferFuncIdent(templateDataTypeIdent)), Args: []ast.Expr{ast.NewIdent(bufIdent)}}},
+	}
+}
+
+// bufferPoolDecls declares the sync.Pool of template buffers shared by the handlers and a function
+// to return a buffer to the pool. Buffers that grew larger than maxPooledBufferSize are dropped.
+// The identifiers are derived from the template data type name so routes generated in the same package do not conflict.
+func bufferPoolDecls(file *source.File, templateDataTypeIdent string) []ast.Decl {
+	const bufIdent = "buf"
+	poolIdent := bufferPoolVarIdent(templateDataTypeIdent)
+	bytesBuffer := func() *ast.SelectorExpr {
+		return &ast.SelectorExpr{X: ast.NewIdent(file.Import("", "bytes")), Sel: ast.NewIdent("Buffer")}
+	}
+	return []ast.Decl{
+		&ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{&ast.ValueSpec{
+				Names: []*ast.Ident{ast.NewIdent(poolIdent)},
+				Values: []ast.Expr{&ast.CompositeLit{
+					Type: &ast.SelectorExpr{X: ast.NewIdent(file.Import("", "sync")), Sel: ast.NewIdent("Pool")},
+					Elts: []ast.Expr{&ast.KeyValueExpr{
+						Key: ast.NewIdent("New"),
+						Value: &ast.FuncLit{
+							Type: &ast.FuncType{Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("any")}}}},
+							Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{
+								&ast.CallExpr{Fun: ast.NewIdent("new"), Args: []ast.Expr{bytesBuffer()}},
+							}}}},
+						},
+					}},
+				}},
+			}},
+		},
+		&ast.FuncDecl{
+			Name: ast.NewIdent(putBufferFuncIdent(templateDataTypeIdent)),
+			Type: &ast.FuncType{Params: &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(bufIdent)}, Type: &ast.StarExpr{X: bytesBuffer()}}}}},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.IfStmt{
+					Cond: &ast.BinaryExpr{
+						X:  &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(bufIdent), Sel: ast.NewIdent("Cap")}},
+						Op: token.GTR,
+						Y:  byteSizeExpr(maxPooledBufferSize),
 					},
-					&ast.ReturnStmt{},
+					Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ReturnStmt{}}},
 				},
+				&ast.ExprStmt{X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(bufIdent), Sel: ast.NewIdent("Reset")}}},
+				&ast.ExprStmt{X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(poolIdent), Sel: ast.NewIdent("Put")}, Args: []ast.Expr{ast.NewIdent(bufIdent)}}},
+			}},
+		},
+	}
+}
+
+// streamTemplateStatements writes the status code and headers and then execute the template to a buffered flushWriter.
+// Since the status code has already been written, a template execution error is only logged.
+func streamTemplateStatements(file *source.File, t *Template, resultType types.Type, templateDataTypeIdent, templatesVariableIdent, resultDataIdent string) []ast.Stmt {
+	const (
+		writerIdent     = "bw"
+		statusCodeIdent = "statusCode"
+	)
+	return []ast.Stmt{
+		setContentTypeHeaderSetOnTemplateData(),
+		singleAssignment(token.DEFINE, ast.NewIdent(statusCodeIdent))(statusCodeExpression(file, resultType, t.defaultStatusCode, resultDataIdent)),
+		callWriteHeader(ast.NewIdent(statusCodeIdent)),
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(writerIdent)},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{file.Call("", "bufio", "NewWriter", []ast.Expr{&ast.CompositeLit{
+				Type: ast.NewIdent(flushWriterTypeIdent(templateDataTypeIdent)),
+				Elts: []ast.Expr{ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse)},
+			}})},
+		},
+		&ast.IfStmt{
+			Init: singleAssignment(token.DEFINE, ast.NewIdent(errIdent))(&ast.CallExpr{
+				Fun:  &ast.SelectorExpr{X: ast.NewIdent(templatesVariableIdent), Sel: ast.NewIdent("ExecuteTemplate")},
+				Args: []ast.Expr{ast.NewIdent(writerIdent), source.String(t.name), ast.NewIdent(resultDataIdent)},
+			}),
+			Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: source.Nil()},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.ExprStmt{X: executeTemplateFailedLogLine(file, routesLogger(t), executeTemplateErrorMessage, errIdent, handlerLogAttributes(file, t, ast.NewIdent(statusCodeIdent))...)},
+			}},
+		},
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent("_")},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(writerIdent), Sel: ast.NewIdent("Flush")}}},
+		},
+	}
+}
+
+// flushWriterTypeDecls declares an io.Writer that flushes the response after each write.
+func flushWriterTypeDecls(file *source.File, templateDataTypeIdent string) []ast.Decl {
+	const (
+		writerIdent = "w"
+		bufIdent    = "p"
+		nIdent      = "n"
+	)
+	typeIdent := flushWriterTypeIdent(templateDataTypeIdent)
+	responseWriter := &ast.SelectorExpr{X: ast.NewIdent(writerIdent), Sel: ast.NewIdent(httpResponseWriterIdent)}
+	return []ast.Decl{
+		&ast.GenDecl{
+			Tok: token.TYPE,
+			Specs: []ast.Spec{&ast.TypeSpec{
+				Name: ast.NewIdent(typeIdent),
+				Type: &ast.StructType{Fields: &ast.FieldList{List: []*ast.Field{{Type: file.HTTPResponseWriter()}}}},
+			}},
+		},
+		&ast.FuncDecl{
+			Recv: &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(writerIdent)}, Type: ast.NewIdent(typeIdent)}}},
+			Name: ast.NewIdent("Write"),
+			Type: &ast.FuncType{
+				Params:  &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{ast.NewIdent(bufIdent)}, Type: &ast.ArrayType{Elt: ast.NewIdent("byte")}}}},
+				Results: &ast.FieldList{List: []*ast.Field{{Type: ast.NewIdent("int")}, {Type: ast.NewIdent("error")}}},
 			},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent(nIdent), ast.NewIdent(errIdent)},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{&ast.CallExpr{Fun: &ast.SelectorExpr{X: responseWriter, Sel: ast.NewIdent("Write")}, Args: []ast.Expr{ast.NewIdent(bufIdent)}}},
+				},
+				&ast.IfStmt{
+					Cond: &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.EQL, Y: source.Nil()},
+					Body: &ast.BlockStmt{List: []ast.Stmt{&ast.AssignStmt{
+						Lhs: []ast.Expr{ast.NewIdent("_")},
+						Tok: token.ASSIGN,
+						Rhs: []ast.Expr{&ast.CallExpr{Fun: &ast.SelectorExpr{
+							X:   file.Call("", "net/http", "NewResponseController", []ast.Expr{responseWriter}),
+							Sel: ast.NewIdent("Flush"),
+						}}},
+					}}},
+				},
+				&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent(nIdent), ast.NewIdent(errIdent)}},
+			}},
 		},
 	}
-	return append(list, writeBodyAndWriteHeadersFunc(file, bufIdent, statusCode)...)
 }
 
-func executeTemplateFailedLogLine(file *source.File, message, errIdent string) *ast.CallExpr {
+// negotiateJSONStatements writes the template data as JSON and returns when the request accepts application/json.
+// The Vary header is set for both the JSON and the HTML response so caches keep them apart.
+// It returns no statements when JSON negotiation is not enabled.
+func negotiateJSONStatements(file *source.File, t *Template, resultType types.Type, fallbackStatusCode int, resultDataIdent string) []ast.Stmt {
+	if !t.negotiateJSON {
+		return nil
+	}
+	return []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{
+		Fun: &ast.SelectorExpr{X: &ast.CallExpr{Fun: &ast.SelectorExpr{
+			X:   &ast.SelectorExpr{X: ast.NewIdent(resultDataIdent), Sel: ast.NewIdent(TemplateNameScopeIdentifierHTTPResponse)},
+			Sel: ast.NewIdent("Header"),
+		}}, Sel: ast.NewIdent("Add")},
+		Args: []ast.Expr{source.String("vary"), source.String("Accept")},
+	}}, &ast.IfStmt{
+		Cond: &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(resultDataIdent), Sel: ast.NewIdent(templateDataAcceptsJSONMethod)}},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.ExprStmt{X: &ast.CallExpr{
+				Fun:  &ast.SelectorExpr{X: ast.NewIdent(resultDataIdent), Sel: ast.NewIdent(templateDataWriteJSONMethod)},
+				Args: []ast.Expr{statusCodeExpression(file, resultType, fallbackStatusCode, resultDataIdent)},
+			}},
+			&ast.ReturnStmt{},
+		}},
+	}}
+}
+
+// executeTemplateFailedLogLine logs an error with the request method, path, and pattern.
+// The attrs are added before the error. When logger is nil, the default slog logger is used.
+func executeTemplateFailedLogLine(file *source.File, logger ast.Expr, message, errIdent string, attrs ...ast.Expr) *ast.CallExpr {
 	args := []ast.Expr{
 		&ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Context")}},
 		source.String(message),
 
+		file.SlogString("method", &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Method")}),
 		file.SlogString("path", &ast.SelectorExpr{
 			X:   &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("URL")},
 			Sel: ast.NewIdent("Path"),
 		}),
 		file.SlogString("pattern", &ast.SelectorExpr{X: ast.NewIdent(TemplateNameScopeIdentifierHTTPRequest), Sel: ast.NewIdent("Pattern")}),
-		file.SlogString("error", source.CallError(errIdent)),
 	}
-	return file.Call("", "log/slog", "ErrorContext", args)
+	args = append(args, attrs...)
+	args = append(args, file.SlogString("error", source.CallError(errIdent)))
+	return logErrorContextCall(file, logger, args)
+}
+
+func logErrorContextCall(file *source.File, logger ast.Expr, args []ast.Expr) *ast.CallExpr {
+	if logger == nil {
+		return file.Call("", "log/slog", "ErrorContext", args)
+	}
+	return &ast.CallExpr{Fun: &ast.SelectorExpr{X: logger, Sel: ast.NewIdent("ErrorContext")}, Args: args}
+}
+
+// routesLogger returns the routes function logger parameter for log lines in handlers.
+// It returns nil when the routes function does not have one.
+func routesLogger(t *Template) ast.Expr {
+	if !t.loggerParam {
+		return nil
+	}
+	return ast.NewIdent(loggerParamName)
+}
+
+// handlerLogAttributes are the log attributes for template errors logged in a handler.
+func handlerLogAttributes(file *source.File, t *Template, statusCode ast.Expr) []ast.Expr {
+	return []ast.Expr{
+		file.SlogString("template", source.String(t.name)),
+		file.SlogString("receiver_method", source.String(t.Method())),
+		slogStatusCode(file, statusCode),
+	}
+}
+
+func slogStatusCode(file *source.File, statusCode ast.Expr) ast.Expr {
+	return file.Call("", "log/slog", "Int", []ast.Expr{source.String("status_code"), statusCode})
 }
 
 type forest template.Template
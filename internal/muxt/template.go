@@ -64,9 +64,33 @@ type Template struct {
 	pathValueTypes map[string]types.Type
 	pathValueNames []string
 
+	// queryType is the type of the query argument; the route path method encodes values of this type in the query string
+	queryType types.Type
+
 	identifier string
 
 	hasResponseWriterArg bool
+
+	// negotiateJSON is set when the handler should encode the result as JSON for requests accepting application/json
+	negotiateJSON bool
+
+	// streamResponse is set when the handler should execute the template directly to the response
+	streamResponse bool
+
+	// errorTemplates is set when error responses should use templates named for the status code
+	errorTemplates bool
+
+	// recoverPanics is set when the handler should recover panics and render the error response
+	recoverPanics bool
+
+	// loggerParam is set when the routes function has a *slog.Logger parameter
+	loggerParam bool
+
+	// middleware is set when the handler should be wrapped by the routes function middleware parameter
+	middleware bool
+
+	// muxInterface is set when the routes function mux parameter is an interface with a Handle method
+	muxInterface bool
 }
 
 func newTemplate(in string) (Template, error, bool) {
@@ -114,7 +138,7 @@ func newTemplate(in string) (Template, error, bool) {
 	switch p.method {
 	default:
 		return p, fmt.Errorf("%s method not allowed", p.method), true
-	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	case "", http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
 	}
 
 	pathValueNames := p.parsePathValueNames()
@@ -183,7 +207,7 @@ func checkPathValueNames(in []string) error {
 		if slices.Contains(in[:i], n) {
 			return fmt.Errorf("forbidden repeated path parameter names: found at least 2 path parameters with name %q", n)
 		}
-		if slices.Contains(patternScope(), n) {
+		if slices.Contains(pathParameterScope(), n) {
 			return fmt.Errorf("the name %s is not allowed as a path parameter it is already in scope", n)
 		}
 	}
@@ -203,6 +227,28 @@ func (t Template) Template() *template.Template {
 	return t.template
 }
 
+// writesBody is false for HEAD routes; the handler still renders the template so the headers
+// (including content-length) match the equivalent GET response.
+func (t Template) writesBody() bool { return t.method != http.MethodHead }
+
+// addPathPrefix prepends the path of prefix to the route path.
+// A prefix host (the part before the first "/") is used for routes without a host.
+func (t *Template) addPathPrefix(prefix string) error {
+	i := strings.Index(prefix, "/")
+	if i < 0 {
+		return fmt.Errorf("path prefix %q must have a path", prefix)
+	}
+	host, path := prefix[:i], strings.TrimSuffix(prefix[i:], "/")
+	if host != "" && t.host != "" {
+		return fmt.Errorf("route %q already has a host so the path prefix %q can not have one", t.name, prefix)
+	}
+	hostAndPath := t.host + t.path
+	t.host = cmp.Or(t.host, host)
+	t.path = path + t.path
+	t.pattern = strings.TrimSuffix(t.pattern, hostAndPath) + t.host + t.path
+	return nil
+}
+
 func (t Template) byPathThenMethod(d Template) int {
 	if n := cmp.Compare(t.path, d.path); n != 0 {
 		return n
@@ -238,6 +284,11 @@ func parseHandler(fileSet *token.FileSet, def *Template, pathParameterNames []st
 	if err := checkArguments(scope, call); err != nil {
 		return err
 	}
+	for _, n := range pathParameterNames {
+		if slices.Contains(patternScope(), n) && isArgument(call, n) {
+			return fmt.Errorf("the path parameter %s can not be used as a call argument it is already in scope; rename the path parameter", n)
+		}
+	}
 
 	def.fun = fun
 	def.call = call
@@ -302,11 +353,31 @@ func checkArguments(identifiers []string, call *ast.CallExpr) error {
 	return nil
 }
 
+func isArgument(call *ast.CallExpr, name string) bool {
+	for _, a := range call.Args {
+		switch exp := a.(type) {
+		case *ast.Ident:
+			if exp.Name == name {
+				return true
+			}
+		case *ast.CallExpr:
+			if isArgument(exp, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 const (
 	TemplateNameScopeIdentifierHTTPRequest  = "request"
 	TemplateNameScopeIdentifierHTTPResponse = "response"
 	TemplateNameScopeIdentifierContext      = "ctx"
 	TemplateNameScopeIdentifierForm         = "form"
+	TemplateNameScopeIdentifierQuery        = "query"
+	TemplateNameScopeIdentifierHeader       = "header"
+	TemplateNameScopeIdentifierCookie       = "cookie"
+	TemplateNameScopeIdentifierBody         = "body"
 
 	TemplateDataFieldIdentifierResult      = "result"
 	TemplateDataFieldIdentifierOkay        = "okay"
@@ -316,7 +387,8 @@ const (
 	TemplateDataFieldIdentifierStatusCode  = "statusCode"
 )
 
-func patternScope() []string {
+// pathParameterScope identifiers can not be used as path parameter names.
+func pathParameterScope() []string {
 	return []string{
 		TemplateNameScopeIdentifierHTTPRequest,
 		TemplateNameScopeIdentifierHTTPResponse,
@@ -325,6 +397,17 @@ func patternScope() []string {
 	}
 }
 
+// patternScope identifiers may be used as call arguments. The ones not in pathParameterScope
+// may be path parameter names when the call does not have them as arguments.
+func patternScope() []string {
+	return append(pathParameterScope(),
+		TemplateNameScopeIdentifierQuery,
+		TemplateNameScopeIdentifierHeader,
+		TemplateNameScopeIdentifierCookie,
+		TemplateNameScopeIdentifierBody,
+	)
+}
+
 func (t Template) matchReceiver(funcDecl *ast.FuncDecl, receiverTypeIdent string) bool {
 	if funcDecl == nil || funcDecl.Name == nil || funcDecl.Name.Name != t.fun.Name ||
 		funcDecl.Recv == nil || len(funcDecl.Recv.List) < 1 {
@@ -338,7 +421,32 @@ func (t Template) matchReceiver(funcDecl *ast.FuncDecl, receiverTypeIdent string
 	return ok && ident.Name == receiverTypeIdent
 }
 
-func (t Template) callHandleFunc(handlerFuncLit *ast.FuncLit) *ast.ExprStmt {
+func (t Template) callHandleFunc(file *source.File, handlerFuncLit *ast.FuncLit) *ast.ExprStmt {
+	if t.middleware {
+		var receiverMethod string
+		if t.fun != nil {
+			receiverMethod = t.fun.Name
+		}
+		return &ast.ExprStmt{X: &ast.CallExpr{
+			Fun: &ast.SelectorExpr{X: ast.NewIdent(muxVarIdent), Sel: ast.NewIdent(httpHandleIdent)},
+			Args: []ast.Expr{source.String(t.pattern), &ast.CallExpr{
+				Fun: ast.NewIdent(middlewareParamName),
+				Args: []ast.Expr{source.String(t.pattern), source.String(receiverMethod), &ast.CallExpr{
+					Fun:  &ast.SelectorExpr{X: ast.NewIdent(file.AddNetHTTP()), Sel: ast.NewIdent("HandlerFunc")},
+					Args: []ast.Expr{handlerFuncLit},
+				}},
+			}},
+		}}
+	}
+	if t.muxInterface {
+		return &ast.ExprStmt{X: &ast.CallExpr{
+			Fun: &ast.SelectorExpr{X: ast.NewIdent(muxVarIdent), Sel: ast.NewIdent(httpHandleIdent)},
+			Args: []ast.Expr{source.String(t.pattern), &ast.CallExpr{
+				Fun:  &ast.SelectorExpr{X: ast.NewIdent(file.AddNetHTTP()), Sel: ast.NewIdent("HandlerFunc")},
+				Args: []ast.Expr{handlerFuncLit},
+			}},
+		}}
+	}
 	return &ast.ExprStmt{X: &ast.CallExpr{
 		Fun: &ast.SelectorExpr{
 			X:   ast.NewIdent(muxVarIdent),
@@ -207,11 +207,33 @@ func TestNewTemplateName(t *testing.T) {
 			},
 		},
 		{
-			Name:     "put root",
+			Name:     "head root",
+			In:       "HEAD /",
+			ExpMatch: true,
+			TemplateName: func(t *testing.T, pat Template) {
+				assert.Equal(t, http.MethodHead, pat.method)
+				assert.Equal(t, "/", pat.path)
+				assert.Equal(t, "HEAD /", pat.pattern)
+				assert.False(t, pat.writesBody())
+			},
+		},
+		{
+			Name:     "options root",
 			In:       "OPTIONS /",
 			ExpMatch: true,
+			TemplateName: func(t *testing.T, pat Template) {
+				assert.Equal(t, http.MethodOptions, pat.method)
+				assert.Equal(t, "/", pat.path)
+				assert.Equal(t, "OPTIONS /", pat.pattern)
+				assert.True(t, pat.writesBody())
+			},
+		},
+		{
+			Name:     "trace root",
+			In:       "TRACE /",
+			ExpMatch: true,
 			Error: func(t *testing.T, err error) {
-				assert.ErrorContains(t, err, "OPTIONS method not allowed")
+				assert.ErrorContains(t, err, "TRACE method not allowed")
 			},
 		},
 		{
@@ -307,6 +329,19 @@ func TestNewTemplateName(t *testing.T) {
 				assert.ErrorContains(t, err, "the name response is not allowed as a path parameter it is already in scope")
 			},
 		},
+		{
+			Name:     "when the path parameter has a name only in scope for call arguments",
+			In:       "GET /{query} F()",
+			ExpMatch: true,
+		},
+		{
+			Name:     "when the path parameter has a name in scope for call arguments and is an argument",
+			In:       "GET /{query} F(S(query))",
+			ExpMatch: true,
+			Error: func(t *testing.T, err error) {
+				assert.ErrorContains(t, err, "the path parameter query can not be used as a call argument it is already in scope")
+			},
+		},
 		{
 			Name:     "when the expression is not a call",
 			In:       "GET / F",
@@ -485,3 +520,34 @@ func TestNewTemplateName(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplate_addPathPrefix(t *testing.T) {
+	for _, tt := range []struct {
+		Name, Prefix          string
+		Host, Path, Pattern   string
+		ExpectedErrorContains string
+	}{
+		{Name: "GET /article/{id}", Prefix: "/admin", Path: "/admin/article/{id}", Pattern: "GET /admin/article/{id}"},
+		{Name: "GET /{$}", Prefix: "/admin/", Path: "/admin/{$}", Pattern: "GET /admin/{$}"},
+		{Name: "/", Prefix: "/admin", Path: "/admin/", Pattern: "/admin/"},
+		{Name: "GET /", Prefix: "tenant.example.com", ExpectedErrorContains: "must have a path"},
+		{Name: "GET /x F()", Prefix: "tenant.example.com/admin", Host: "tenant.example.com", Path: "/admin/x", Pattern: "GET tenant.example.com/admin/x"},
+		{Name: "GET example.com/x", Prefix: "/admin", Host: "example.com", Path: "/admin/x", Pattern: "GET example.com/admin/x"},
+		{Name: "GET example.com/x", Prefix: "tenant.example.com/admin", ExpectedErrorContains: "already has a host"},
+	} {
+		t.Run(tt.Name+" "+tt.Prefix, func(t *testing.T) {
+			route, err, ok := newTemplate(tt.Name)
+			require.True(t, ok)
+			require.NoError(t, err)
+			err = route.addPathPrefix(tt.Prefix)
+			if tt.ExpectedErrorContains != "" {
+				assert.ErrorContains(t, err, tt.ExpectedErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.Host, route.host)
+			assert.Equal(t, tt.Path, route.path)
+			assert.Equal(t, tt.Pattern, route.pattern)
+		})
+	}
+}
@@ -11,7 +11,7 @@ import (
 
 func TestTemplates(t *testing.T) {
 	t.Run("when one of the template names is a malformed pattern", func(t *testing.T) {
-		ts := template.Must(template.New("").Parse(`{{define "HEAD /"}}{{end}}`))
+		ts := template.Must(template.New("").Parse(`{{define "CONNECT /"}}{{end}}`))
 		_, err := muxt.Templates(ts)
 		require.Error(t, err)
 	})
@@ -208,6 +208,18 @@ func (file *File) TimeParseCall(layout string, expr ast.Expr) *ast.CallExpr {
 	return file.Call("", "time", "Parse", []ast.Expr{String(layout), expr})
 }
 
+func (file *File) TimeParseDurationCall(expr ast.Expr) *ast.CallExpr {
+	return file.Call("", "time", "ParseDuration", []ast.Expr{expr})
+}
+
+func (file *File) StrconvParseFloat32Call(in ast.Expr) *ast.CallExpr {
+	return file.StrconvParseFloatCall(in, 32)
+}
+
+func (file *File) StrconvParseFloat64Call(in ast.Expr) *ast.CallExpr {
+	return file.StrconvParseFloatCall(in, 64)
+}
+
 func (file *File) BytesNewBuffer(expr ast.Expr) *ast.CallExpr {
 	return &ast.CallExpr{
 		Fun: &ast.SelectorExpr{
@@ -347,6 +359,20 @@ func (file *File) FormatUint64(in ast.Expr) *ast.CallExpr {
 	}
 }
 
+func (file *File) FormatFloat32(in ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(file.Import("", "strconv")), Sel: ast.NewIdent("FormatFloat")},
+		Args: []ast.Expr{&ast.CallExpr{Fun: ast.NewIdent("float64"), Args: []ast.Expr{in}}, &ast.BasicLit{Kind: token.CHAR, Value: "'f'"}, Int(-1), Int(32)},
+	}
+}
+
+func (file *File) FormatFloat64(in ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: ast.NewIdent(file.Import("", "strconv")), Sel: ast.NewIdent("FormatFloat")},
+		Args: []ast.Expr{in, &ast.BasicLit{Kind: token.CHAR, Value: "'f'"}, Int(-1), Int(64)},
+	}
+}
+
 func (file *File) FormatBool(in ast.Expr) *ast.CallExpr {
 	return &ast.CallExpr{
 		Fun:  &ast.SelectorExpr{X: ast.NewIdent(file.Import("", "strconv")), Sel: ast.NewIdent("FormatBool")},
@@ -378,6 +404,10 @@ func (file *File) Format(variable ast.Expr, kind types.BasicKind) (ast.Expr, err
 		return file.FormatUint32(variable), nil
 	case types.Uint64:
 		return file.FormatUint64(variable), nil
+	case types.Float32:
+		return file.FormatFloat32(variable), nil
+	case types.Float64, types.UntypedFloat:
+		return file.FormatFloat64(variable), nil
 	case types.String:
 		return variable, nil
 	default:
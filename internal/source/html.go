@@ -6,10 +6,13 @@ import (
 	"go/ast"
 	"go/token"
 	"go/types"
+	"math"
+	"reflect"
 	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/crhntr/dom/spec"
 	"golang.org/x/net/html/atom"
@@ -23,36 +26,103 @@ type (
 )
 
 func ParseInputValidations(name string, input spec.Element, tp types.Type) ([]ValidationGenerator, error) {
-	if tag := strings.ToLower(input.TagName()); tag != atom.Input.String() {
-		return nil, fmt.Errorf("expected element to have tag <input> got <%s>", tag)
+	switch tag := strings.ToLower(input.TagName()); tag {
+	case atom.Input.String():
+	case atom.Select.String():
+		return parseSelectValidations(name, input, tp)
+	case atom.Textarea.String():
+		return parseLengthValidations(name, input)
+	default:
+		return nil, fmt.Errorf("expected element to have tag <input>, <select>, or <textarea> got <%s>", tag)
 	}
 	var result []ValidationGenerator
 	typeAttr := cmp.Or(input.GetAttribute("type"), "text")
-	if slices.Contains([]string{
+	if typeAttr == "file" {
+		return parseFileInputValidations(name, input), nil
+	}
+	switch {
+	case IsNamed(tp, "time", "Duration"):
+		if input.HasAttribute("min") {
+			d, err := time.ParseDuration(input.GetAttribute("min"))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, MinDurationValidation{Name: name, Min: d})
+		}
+		if input.HasAttribute("max") {
+			d, err := time.ParseDuration(input.GetAttribute("max"))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, MaxDurationValidation{Name: name, Max: d})
+		}
+	case IsNamed(tp, "time", "Time"):
+		if input.HasAttribute("min") {
+			val := input.GetAttribute("min")
+			t, err := parseTimeInputValue(input, val)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, MinTimeValidation{Name: name, Min: t, Value: val})
+		}
+		if input.HasAttribute("max") {
+			val := input.GetAttribute("max")
+			t, err := parseTimeInputValue(input, val)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, MaxTimeValidation{Name: name, Max: t, Value: val})
+		}
+	case slices.Contains([]string{
 		"date", "month", "week", "time", "datetime-local", "number", "range",
-	}, typeAttr) {
+	}, typeAttr):
 		if input.HasAttribute("min") {
 			val := input.GetAttribute("min")
-			_, err := ParseStringWithType(val, tp)
+			v, err := ParseStringWithType(val, tp)
+			if err != nil {
+				return nil, err
+			}
+			lit, err := numberLiteral("min", val, v)
 			if err != nil {
 				return nil, err
 			}
 			result = append(result, MinValidation{
 				Name:   name,
-				MinExp: &ast.BasicLit{Value: val, Kind: token.INT},
+				MinExp: lit,
 			})
 		}
 		if input.HasAttribute("max") {
 			val := input.GetAttribute("max")
-			_, err := ParseStringWithType(val, tp)
+			v, err := ParseStringWithType(val, tp)
+			if err != nil {
+				return nil, err
+			}
+			lit, err := numberLiteral("max", val, v)
 			if err != nil {
 				return nil, err
 			}
 			result = append(result, MaxValidation{
 				Name:   name,
-				MinExp: &ast.BasicLit{Value: val, Kind: token.INT},
+				MinExp: lit,
 			})
 		}
+		if step := input.GetAttribute("step"); (typeAttr == "number" || typeAttr == "range") && step != "" && step != "any" {
+			validation, err := parseStepValidation(name, step, cmp.Or(input.GetAttribute("min"), "0"), tp)
+			if err != nil {
+				return nil, err
+			}
+			if validation != nil {
+				result = append(result, validation)
+			}
+		}
+	}
+	if basic, ok := tp.Underlying().(*types.Basic); ok && basic.Info()&types.IsString != 0 {
+		switch {
+		case typeAttr == "email" && !input.HasAttribute("multiple"):
+			result = append(result, EmailValidation{Name: name})
+		case typeAttr == "url":
+			result = append(result, URLValidation{Name: name})
+		}
 	}
 	if slices.Contains([]string{
 		"text", "search", "url", "tel", "email", "password",
@@ -67,7 +137,18 @@ func ParseInputValidations(name string, input spec.Element, tp types.Type) ([]Va
 			Exp:  exp,
 		})
 	}
-	var minL MinLengthValidation
+	lengthValidations, err := parseLengthValidations(name, input)
+	if err != nil {
+		return nil, err
+	}
+	return append(result, lengthValidations...), nil
+}
+
+func parseLengthValidations(name string, input spec.Element) ([]ValidationGenerator, error) {
+	var (
+		result []ValidationGenerator
+		minL   MinLengthValidation
+	)
 	if val := input.GetAttribute("minlength"); val != "" {
 		n, err := strconv.Atoi(val)
 		if err != nil {
@@ -102,3 +183,155 @@ func ParseInputValidations(name string, input spec.Element, tp types.Type) ([]Va
 	}
 	return result, nil
 }
+
+// parseStepValidation returns a nil ValidationGenerator when every value of tp satisfies the step.
+func parseStepValidation(name, step, base string, tp types.Type) (ValidationGenerator, error) {
+	basic, ok := tp.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsNumeric == 0 {
+		return nil, fmt.Errorf("step requires a number type got %s", tp)
+	}
+	stepValue, err := ParseStringWithType(step, tp)
+	if err != nil {
+		return nil, fmt.Errorf("step must be a %s: %w", tp, err)
+	}
+	if positive := stepValue.CanInt() && stepValue.Int() > 0 || stepValue.CanUint() && stepValue.Uint() > 0 || stepValue.CanFloat() && stepValue.Float() > 0; !positive {
+		return nil, fmt.Errorf("step must be positive")
+	}
+	baseValue, err := ParseStringWithType(base, tp)
+	if err != nil {
+		return nil, err
+	}
+	if !stepValue.CanFloat() && stepValue.Equal(reflect.ValueOf(1).Convert(stepValue.Type())) {
+		// every integer is a multiple of 1
+		return nil, nil
+	}
+	stepLit, err := numberLiteral("step", step, stepValue)
+	if err != nil {
+		return nil, err
+	}
+	baseLit, err := numberLiteral("min", base, baseValue)
+	if err != nil {
+		return nil, err
+	}
+	return StepValidation{
+		Name: name,
+		Step: stepLit,
+		Base: baseLit,
+		Kind: basic.Kind(),
+	}, nil
+}
+
+// parseSelectValidations checks the value is one of the option values when the options are not generated by template actions.
+func parseSelectValidations(name string, input spec.Element, tp types.Type) ([]ValidationGenerator, error) {
+	basic, ok := tp.Underlying().(*types.Basic)
+	if !ok || basic.Info()&(types.IsString|types.IsNumeric) == 0 || strings.Contains(input.TextContent(), "{{") {
+		return nil, nil
+	}
+	options := input.QuerySelectorAll("option")
+	validation := OneOfValidation{Name: name}
+	var numbers []string
+	for i := 0; i < options.Length(); i++ {
+		option := options.Item(i)
+		val := strings.Join(strings.Fields(option.TextContent()), " ")
+		if option.HasAttribute("value") {
+			val = option.GetAttribute("value")
+		}
+		if strings.Contains(val, "{{") {
+			return nil, nil
+		}
+		if basic.Info()&types.IsString != 0 {
+			if !slices.Contains(validation.Options, val) {
+				validation.Values = append(validation.Values, String(val))
+				validation.Options = append(validation.Options, val)
+			}
+			continue
+		}
+		if val == "" {
+			continue
+		}
+		v, err := ParseStringWithType(val, tp)
+		if err != nil {
+			return nil, fmt.Errorf("option value for %s: %w", name, err)
+		}
+		lit, err := numberLiteral("option value for "+name, val, v)
+		if err != nil {
+			return nil, err
+		}
+		if key := fmt.Sprint(v.Interface()); !slices.Contains(numbers, key) {
+			numbers = append(numbers, key)
+			validation.Values = append(validation.Values, lit)
+			validation.Options = append(validation.Options, val)
+		}
+	}
+	if len(validation.Values) == 0 {
+		return nil, nil
+	}
+	return []ValidationGenerator{validation}, nil
+}
+
+func parseFileInputValidations(name string, input spec.Element) []ValidationGenerator {
+	var result []ValidationGenerator
+	if !input.HasAttribute("multiple") {
+		result = append(result, SingleFileValidation{Name: name})
+	}
+	var accept []string
+	for _, a := range strings.Split(input.GetAttribute("accept"), ",") {
+		if a = strings.ToLower(strings.TrimSpace(a)); a != "" {
+			accept = append(accept, a)
+		}
+	}
+	if len(accept) > 0 {
+		result = append(result, AcceptValidation{Name: name, Accept: accept})
+	}
+	return result
+}
+
+// numberLiteral returns a literal with the attribute text for the parsed number v.
+// Float values like "NaN" and "Inf" are rejected since they are not Go literals.
+func numberLiteral(attribute, text string, v reflect.Value) (*ast.BasicLit, error) {
+	if v.CanFloat() && (math.IsNaN(v.Float()) || math.IsInf(v.Float(), 0)) {
+		return nil, fmt.Errorf("%s must be a finite number got %q", attribute, text)
+	}
+	return &ast.BasicLit{Value: text, Kind: numberLiteralKind(v)}, nil
+}
+
+func numberLiteralKind(v reflect.Value) token.Token {
+	if v.CanFloat() {
+		return token.FLOAT
+	}
+	return token.INT
+}
+
+// TimeInputLayout returns the time.Parse layout for the value of a date, datetime-local, month, or time input.
+// Seconds are included when the step attribute is not a whole number of minutes.
+func TimeInputLayout(input spec.Element) (string, bool) {
+	seconds := ""
+	if step := input.GetAttribute("step"); step != "" {
+		if n, err := strconv.ParseFloat(step, 64); err != nil || math.Mod(n, 60) != 0 {
+			seconds = ":05"
+		}
+	}
+	switch input.GetAttribute("type") {
+	case "date":
+		return time.DateOnly, true
+	case "month":
+		return "2006-01", true
+	case "time":
+		return "15:04" + seconds, true
+	case "datetime-local":
+		return "2006-01-02T15:04" + seconds, true
+	default:
+		return "", false
+	}
+}
+
+func parseTimeInputValue(input spec.Element, value string) (time.Time, error) {
+	if input.GetAttribute("type") == "week" {
+		return ParseWeekInputValue(value)
+	}
+	layout, ok := TimeInputLayout(input)
+	if !ok {
+		return time.Time{}, fmt.Errorf("min and max for time.Time require an input with type date, datetime-local, month, time, or week")
+	}
+	return time.Parse(layout, value)
+}
@@ -6,6 +6,9 @@ import (
 	"go/token"
 	"go/types"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/crhntr/dom/spec"
 )
@@ -60,6 +63,72 @@ func (val MaxValidation) GenerateValidation(_ *File, variable ast.Expr, handleEr
 	}
 }
 
+type MinTimeValidation struct {
+	Name  string
+	Min   time.Time
+	Value string
+}
+
+func (val MinTimeValidation) GenerateValidation(imports *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	return &ast.IfStmt{
+		Cond: &ast.CallExpr{Fun: &ast.SelectorExpr{X: variable, Sel: ast.NewIdent("Before")}, Args: []ast.Expr{timeDateCall(imports, val.Min)}},
+		Body: handleError(fmt.Sprintf("%s must not be before %s", val.Name, val.Value)),
+	}
+}
+
+type MaxTimeValidation struct {
+	Name  string
+	Max   time.Time
+	Value string
+}
+
+func (val MaxTimeValidation) GenerateValidation(imports *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	return &ast.IfStmt{
+		Cond: &ast.CallExpr{Fun: &ast.SelectorExpr{X: variable, Sel: ast.NewIdent("After")}, Args: []ast.Expr{timeDateCall(imports, val.Max)}},
+		Body: handleError(fmt.Sprintf("%s must not be after %s", val.Name, val.Value)),
+	}
+}
+
+func timeDateCall(imports *File, t time.Time) *ast.CallExpr {
+	return imports.Call("", "time", "Date", []ast.Expr{
+		Int(t.Year()), Int(int(t.Month())), Int(t.Day()),
+		Int(t.Hour()), Int(t.Minute()), Int(t.Second()), Int(t.Nanosecond()),
+		&ast.SelectorExpr{X: ast.NewIdent(imports.Import("", "time")), Sel: ast.NewIdent("UTC")},
+	})
+}
+
+type MinDurationValidation struct {
+	Name string
+	Min  time.Duration
+}
+
+func (val MinDurationValidation) GenerateValidation(_ *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	return &ast.IfStmt{
+		Cond: &ast.BinaryExpr{
+			X:  variable,
+			Op: token.LSS,
+			Y:  &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(int64(val.Min), 10)},
+		},
+		Body: handleError(fmt.Sprintf("%s must not be less than %s", val.Name, val.Min)),
+	}
+}
+
+type MaxDurationValidation struct {
+	Name string
+	Max  time.Duration
+}
+
+func (val MaxDurationValidation) GenerateValidation(_ *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	return &ast.IfStmt{
+		Cond: &ast.BinaryExpr{
+			X:  variable,
+			Op: token.GTR,
+			Y:  &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(int64(val.Max), 10)},
+		},
+		Body: handleError(fmt.Sprintf("%s must not be more than %s", val.Name, val.Max)),
+	}
+}
+
 type PatternValidation struct {
 	Name string
 	Exp  *regexp.Regexp
@@ -112,3 +181,182 @@ func (val MinLengthValidation) GenerateValidation(_ *File, variable ast.Expr, ha
 		Body: handleError(fmt.Sprintf("%s is too short (the min length is %d)", val.Name, val.MinLength)),
 	}
 }
+
+// SingleFileValidation variable must be a []*multipart.FileHeader.
+type SingleFileValidation struct {
+	Name string
+}
+
+func (val SingleFileValidation) GenerateValidation(_ *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	return &ast.IfStmt{
+		Cond: &ast.BinaryExpr{
+			X:  &ast.CallExpr{Fun: ast.NewIdent("len"), Args: []ast.Expr{variable}},
+			Op: token.GTR,
+			Y:  Int(1),
+		},
+		Body: handleError(fmt.Sprintf("%s accepts a single file", val.Name)),
+	}
+}
+
+// AcceptValidation variable must be a []*multipart.FileHeader.
+type AcceptValidation struct {
+	Name   string
+	Accept []string
+}
+
+func (val AcceptValidation) GenerateValidation(imports *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	const fileHeaderIdent = "fh"
+	contentType := &ast.CallExpr{
+		Fun:  &ast.SelectorExpr{X: &ast.SelectorExpr{X: ast.NewIdent(fileHeaderIdent), Sel: ast.NewIdent("Header")}, Sel: ast.NewIdent("Get")},
+		Args: []ast.Expr{String("Content-Type")},
+	}
+	var accepted ast.Expr
+	for _, a := range val.Accept {
+		var exp ast.Expr
+		switch {
+		case strings.HasPrefix(a, "."):
+			exp = imports.Call("", "strings", "EqualFold", []ast.Expr{
+				imports.Call("", "path/filepath", "Ext", []ast.Expr{&ast.SelectorExpr{X: ast.NewIdent(fileHeaderIdent), Sel: ast.NewIdent("Filename")}}),
+				String(a),
+			})
+		case strings.HasSuffix(a, "/*"):
+			exp = imports.Call("", "strings", "HasPrefix", []ast.Expr{contentType, String(strings.TrimSuffix(a, "*"))})
+		default:
+			exp = &ast.BinaryExpr{X: contentType, Op: token.EQL, Y: String(a)}
+		}
+		if accepted == nil {
+			accepted = exp
+			continue
+		}
+		accepted = &ast.BinaryExpr{X: accepted, Op: token.LOR, Y: exp}
+	}
+	return &ast.RangeStmt{
+		Key:   ast.NewIdent("_"),
+		Value: ast.NewIdent(fileHeaderIdent),
+		Tok:   token.DEFINE,
+		X:     variable,
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.IfStmt{
+				Cond: &ast.UnaryExpr{Op: token.NOT, X: &ast.ParenExpr{X: accepted}},
+				Body: handleError(fmt.Sprintf("%s must be one of %s", val.Name, strings.Join(val.Accept, ", "))),
+			},
+		}},
+	}
+}
+
+// StepValidation checks the value is Base plus a whole number of Step.
+type StepValidation struct {
+	Name       string
+	Step, Base *ast.BasicLit
+	Kind       types.BasicKind
+}
+
+func (val StepValidation) GenerateValidation(imports *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	message := fmt.Sprintf("%s must be a multiple of %s", val.Name, val.Step.Value)
+	offset := variable
+	if n, err := strconv.ParseFloat(val.Base.Value, 64); err != nil || n != 0 {
+		message = fmt.Sprintf("%s must be a multiple of %s from %s", val.Name, val.Step.Value, val.Base.Value)
+		offset = &ast.ParenExpr{X: &ast.BinaryExpr{X: variable, Op: token.SUB, Y: val.Base}}
+	}
+	if val.Kind != types.Float32 && val.Kind != types.Float64 {
+		return &ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: &ast.BinaryExpr{X: offset, Op: token.REM, Y: val.Step}, Op: token.NEQ, Y: Int(0)},
+			Body: handleError(message),
+		}
+	}
+	if val.Kind == types.Float32 {
+		if paren, ok := offset.(*ast.ParenExpr); ok {
+			offset = paren.X
+		}
+		offset = &ast.CallExpr{Fun: ast.NewIdent("float64"), Args: []ast.Expr{offset}}
+	}
+	const stepsIdent = "steps"
+	return &ast.IfStmt{
+		Init: &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(stepsIdent)},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.BinaryExpr{X: offset, Op: token.QUO, Y: val.Step}},
+		},
+		Cond: &ast.BinaryExpr{
+			X: imports.Call("", "math", "Abs", []ast.Expr{&ast.BinaryExpr{
+				X:  ast.NewIdent(stepsIdent),
+				Op: token.SUB,
+				Y:  imports.Call("", "math", "Round", []ast.Expr{ast.NewIdent(stepsIdent)}),
+			}}),
+			Op: token.GTR,
+			Y:  &ast.BasicLit{Kind: token.FLOAT, Value: "1e-9"},
+		},
+		Body: handleError(message),
+	}
+}
+
+// emailPattern is the valid email address pattern from the HTML specification.
+const emailPattern = `^[a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`
+
+type EmailValidation struct {
+	Name string
+}
+
+func (val EmailValidation) GenerateValidation(imports *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	return &ast.IfStmt{
+		Cond: &ast.BinaryExpr{
+			X:  &ast.BinaryExpr{X: variable, Op: token.NEQ, Y: String("")},
+			Op: token.LAND,
+			Y: &ast.UnaryExpr{Op: token.NOT, X: &ast.CallExpr{
+				Fun:  &ast.SelectorExpr{X: imports.Call("", "regexp", "MustCompile", []ast.Expr{String(emailPattern)}), Sel: ast.NewIdent("MatchString")},
+				Args: []ast.Expr{variable},
+			}},
+		},
+		Body: handleError(fmt.Sprintf("%s must be an email address", val.Name)),
+	}
+}
+
+// URLValidation checks the value is an absolute URL.
+type URLValidation struct {
+	Name string
+}
+
+func (val URLValidation) GenerateValidation(imports *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	const (
+		urlIdent = "u"
+		errIdent = "err"
+	)
+	return &ast.IfStmt{
+		Init: &ast.AssignStmt{
+			Lhs: []ast.Expr{ast.NewIdent(urlIdent), ast.NewIdent(errIdent)},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{imports.Call("", "net/url", "Parse", []ast.Expr{variable})},
+		},
+		Cond: &ast.BinaryExpr{
+			X:  &ast.BinaryExpr{X: variable, Op: token.NEQ, Y: String("")},
+			Op: token.LAND,
+			Y: &ast.ParenExpr{X: &ast.BinaryExpr{
+				X:  &ast.BinaryExpr{X: ast.NewIdent(errIdent), Op: token.NEQ, Y: Nil()},
+				Op: token.LOR,
+				Y:  &ast.UnaryExpr{Op: token.NOT, X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: ast.NewIdent(urlIdent), Sel: ast.NewIdent("IsAbs")}}},
+			}},
+		},
+		Body: handleError(fmt.Sprintf("%s must be an absolute URL", val.Name)),
+	}
+}
+
+// OneOfValidation checks the value is one of the <option> values of a <select>.
+type OneOfValidation struct {
+	Name    string
+	Values  []ast.Expr
+	Options []string
+}
+
+func (val OneOfValidation) GenerateValidation(_ *File, variable ast.Expr, handleError ValidationErrorBlock) ast.Stmt {
+	quoted := make([]string, 0, len(val.Options))
+	for _, option := range val.Options {
+		quoted = append(quoted, strconv.Quote(option))
+	}
+	return &ast.SwitchStmt{
+		Tag: variable,
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.CaseClause{List: val.Values},
+			&ast.CaseClause{Body: handleError(fmt.Sprintf("%s must be one of %s", val.Name, strings.Join(quoted, ", "))).List},
+		}},
+	}
+}
@@ -20,6 +20,10 @@ import (
 )
 
 func Test_inputValidations(t *testing.T) {
+	timePkg := types.NewPackage("time", "time")
+	timeType := types.NewNamed(types.NewTypeName(0, timePkg, "Time", nil), types.NewStruct(nil, nil), nil)
+	durationType := types.NewNamed(types.NewTypeName(0, timePkg, "Duration", nil), types.Typ[types.Int64], nil)
+	fileHeaderSlice := types.NewSlice(types.NewPointer(types.NewNamed(types.NewTypeName(0, types.NewPackage("mime/multipart", "multipart"), "FileHeader", nil), types.NewStruct(nil, nil), nil)))
 	for _, tt := range []struct {
 		Name     string
 		Type     types.Type
@@ -236,7 +240,163 @@ func Test_inputValidations(t *testing.T) {
 			Name:     "wrong tag",
 			Type:     types.Universe.Lookup("int").Type(),
 			Template: `<form type="number" name="field" min="32"></form>`,
-			Error:    `expected element to have tag <input> got <form>`,
+			Error:    `expected element to have tag <input>, <select>, or <textarea> got <form>`,
+		},
+		{
+			Name:     "select",
+			Type:     types.Universe.Lookup("string").Type(),
+			Template: `<select name="field" multiple required><option>a</option><option value="b"> B </option><option>a</option></select>`,
+			Result: `{
+	switch v {
+	case "a", "b":
+	default:
+		http.Error(response, "field must be one of \"a\", \"b\"", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "select number options",
+			Type:     types.Universe.Lookup("int").Type(),
+			Template: `<select name="field"><option value="">Pick one</option><option>1</option><option value="2">Two</option></select>`,
+			Result: `{
+	switch v {
+	case 1, 2:
+	default:
+		http.Error(response, "field must be one of \"1\", \"2\"", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "select template options",
+			Type:     types.Universe.Lookup("string").Type(),
+			Template: `<select name="field">{{range .}}<option>{{.}}</option>{{end}}</select>`,
+			Result: `{
+}`,
+		},
+		{
+			Name:     "select option is not a number",
+			Type:     types.Universe.Lookup("int").Type(),
+			Template: `<select name="field"><option>one</option></select>`,
+			Error:    `option value for field: strconv.ParseInt: parsing "one": invalid syntax`,
+		},
+		{
+			Name:     "textarea",
+			Type:     types.Universe.Lookup("string").Type(),
+			Template: `<textarea name="field" minlength="2" maxlength="10"></textarea>`,
+			Result: `{
+	if len(v) < 2 {
+		http.Error(response, "field is too short (the min length is 2)", http.StatusBadRequest)
+		return
+	}
+	if len(v) > 10 {
+		http.Error(response, "field is too long (the max length is 10)", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "step",
+			Type:     types.Universe.Lookup("int").Type(),
+			Template: `<input type="number" name="field" step="5">`,
+			Result: `{
+	if v%5 != 0 {
+		http.Error(response, "field must be a multiple of 5", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "step from min",
+			Type:     types.Universe.Lookup("uint8").Type(),
+			Template: `<input type="range" name="field" min="1" step="2">`,
+			Result: `{
+	if v < 1 {
+		http.Error(response, "field must not be less than 1", http.StatusBadRequest)
+		return
+	}
+	if (v-1)%2 != 0 {
+		http.Error(response, "field must be a multiple of 2 from 1", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "float step",
+			Type:     types.Universe.Lookup("float32").Type(),
+			Template: `<input type="number" name="field" min="0.5" step="0.25">`,
+			Result: `{
+	if v < 0.5 {
+		http.Error(response, "field must not be less than 0.5", http.StatusBadRequest)
+		return
+	}
+	if steps := float64(v-0.5) / 0.25; math.Abs(steps-math.Round(steps)) > 1e-9 {
+		http.Error(response, "field must be a multiple of 0.25 from 0.5", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "integer step of one",
+			Type:     types.Universe.Lookup("int64").Type(),
+			Template: `<input type="number" name="field" step="1">`,
+			Result: `{
+}`,
+		},
+		{
+			Name:     "step any",
+			Type:     types.Universe.Lookup("float64").Type(),
+			Template: `<input type="number" name="field" step="any">`,
+			Result: `{
+}`,
+		},
+		{
+			Name:     "step is not positive",
+			Type:     types.Universe.Lookup("int").Type(),
+			Template: `<input type="number" name="field" step="0">`,
+			Error:    `step must be positive`,
+		},
+		{
+			Name:     "step is not an integer",
+			Type:     types.Universe.Lookup("int").Type(),
+			Template: `<input type="number" name="field" step="0.5">`,
+			Error:    `step must be a int: strconv.ParseInt: parsing "0.5": invalid syntax`,
+		},
+		{
+			Name:     "min is not finite",
+			Type:     types.Universe.Lookup("float64").Type(),
+			Template: `<input type="number" name="field" min="Inf">`,
+			Error:    `min must be a finite number got "Inf"`,
+		},
+		{
+			Name:     "step is not finite",
+			Type:     types.Universe.Lookup("float64").Type(),
+			Template: `<input type="number" name="field" step="Inf">`,
+			Error:    `step must be a finite number got "Inf"`,
+		},
+		{
+			Name:     "option value is not finite",
+			Type:     types.Universe.Lookup("float64").Type(),
+			Template: `<select name="field"><option>1</option><option>-infinity</option></select>`,
+			Error:    `option value for field must be a finite number got "-infinity"`,
+		},
+		{
+			Name:     "email",
+			Type:     types.Universe.Lookup("string").Type(),
+			Template: `<input type="email" name="field">`,
+			Result:   "{\n\tif v != \"\" && !regexp.MustCompile(\"^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$\").MatchString(v) {\n\t\thttp.Error(response, \"field must be an email address\", http.StatusBadRequest)\n\t\treturn\n\t}\n}",
+		},
+		{
+			Name:     "url",
+			Type:     types.Universe.Lookup("string").Type(),
+			Template: `<input type="url" name="field">`,
+			Result: `{
+	if u, err := url.Parse(v); v != "" && (err != nil || !u.IsAbs()) {
+		http.Error(response, "field must be an absolute URL", http.StatusBadRequest)
+		return
+	}
+}`,
 		},
 		{
 			Name:     "zero max",
@@ -287,6 +447,98 @@ func Test_inputValidations(t *testing.T) {
 		http.Error(response, "field is too short (the min length is 3)", http.StatusBadRequest)
 		return
 	}
+}`,
+		},
+		{
+			Name:     "float min and max",
+			Type:     types.Universe.Lookup("float64").Type(),
+			Template: `<input type="number" name="field" min="0.5" max="10">`,
+			Result: `{
+	if v < 0.5 {
+		http.Error(response, "field must not be less than 0.5", http.StatusBadRequest)
+		return
+	}
+	if v > 10 {
+		http.Error(response, "field must not be more than 10", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "date min and max",
+			Type:     timeType,
+			Template: `<input type="date" name="field" min="2024-01-01" max="2024-12-31">`,
+			Result: `{
+	if v.Before(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		http.Error(response, "field must not be before 2024-01-01", http.StatusBadRequest)
+		return
+	}
+	if v.After(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		http.Error(response, "field must not be after 2024-12-31", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "week min",
+			Type:     timeType,
+			Template: `<input type="week" name="field" min="2025-W02">`,
+			Result: `{
+	if v.Before(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) {
+		http.Error(response, "field must not be before 2025-W02", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "week min is not in the year",
+			Type:     timeType,
+			Template: `<input type="week" name="field" min="2024-W99">`,
+			Error:    `week 99 is not in 2024`,
+		},
+		{
+			Name:     "time min is not a time",
+			Type:     timeType,
+			Template: `<input type="time" name="field" min="noon">`,
+			Error:    `parsing time "noon" as "15:04": cannot parse "noon" as "15"`,
+		},
+		{
+			Name:     "duration min and max",
+			Type:     durationType,
+			Template: `<input name="field" min="1m30s" max="2h">`,
+			Result: `{
+	if v < 90000000000 {
+		http.Error(response, "field must not be less than 1m30s", http.StatusBadRequest)
+		return
+	}
+	if v > 7200000000000 {
+		http.Error(response, "field must not be more than 2h0m0s", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "single file",
+			Type:     fileHeaderSlice,
+			Template: `<input type="file" name="field">`,
+			Result: `{
+	if len(v) > 1 {
+		http.Error(response, "field accepts a single file", http.StatusBadRequest)
+		return
+	}
+}`,
+		},
+		{
+			Name:     "multiple files with accept",
+			Type:     fileHeaderSlice,
+			Template: `<input type="file" name="field" accept=".PNG, image/*,application/pdf" multiple>`,
+			Result: `{
+	for _, fh := range v {
+		if !(strings.EqualFold(filepath.Ext(fh.Filename), ".png") || strings.HasPrefix(fh.Header.Get("Content-Type"), "image/") || fh.Header.Get("Content-Type") == "application/pdf") {
+			http.Error(response, "field must be one of .png, image/*, application/pdf", http.StatusBadRequest)
+			return
+		}
+	}
 }`,
 		},
 	} {
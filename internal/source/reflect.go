@@ -71,7 +71,29 @@ func ParseStringWithType(val string, tp types.Type) (reflect.Value, error) {
 			return reflect.Value{}, err
 		}
 		return reflect.ValueOf(n), nil
+	case reflect.Float32.String():
+		n, err := strconv.ParseFloat(val, 32)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(float32(n)), nil
+	case reflect.Float64.String():
+		n, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n), nil
 	default:
 		return reflect.Value{}, fmt.Errorf("type %s unknown", tp.String())
 	}
 }
+
+// IsNamed reports whether tp is the named type pkgPath.name (for example "time", "Duration").
+func IsNamed(tp types.Type, pkgPath, name string) bool {
+	named, ok := tp.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == pkgPath && obj.Name() == name
+}
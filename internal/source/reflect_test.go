@@ -26,6 +26,9 @@ func TestParseStringWithType(t *testing.T) {
 		{Name: "valid uint16", Value: "32", Type: types.Universe.Lookup("uint16").Type()},
 		{Name: "valid uint32", Value: "32", Type: types.Universe.Lookup("uint32").Type()},
 		{Name: "valid uint64", Value: "32", Type: types.Universe.Lookup("uint64").Type()},
+		{Name: "valid float32", Value: "0.5", Type: types.Universe.Lookup("float32").Type()},
+		{Name: "valid float64", Value: "-1.25", Type: types.Universe.Lookup("float64").Type()},
+		{Name: "invalid float64", Value: "one", Type: types.Universe.Lookup("float64").Type(), ErrorContains: `parsing "one": invalid syntax`},
 	} {
 		t.Run(tt.Name, func(t *testing.T) {
 			_, err := ParseStringWithType(tt.Value, tt.Type)
@@ -0,0 +1,69 @@
+package source
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseWeekInputValue parses the value of a week input (for example "2024-W05") as the Monday starting the ISO week.
+// The week must be in the year, so "2024-W53" is an error since 2024 has 52 ISO weeks.
+func ParseWeekInputValue(value string) (time.Time, error) {
+	if len(value) != len("2006-W01") || value[4:6] != "-W" || strings.Trim(value[:4]+value[6:], "0123456789") != "" {
+		return time.Time{}, fmt.Errorf("week %q is not formatted like 2006-W01", value)
+	}
+	year, _ := strconv.Atoi(value[:4])
+	week, _ := strconv.Atoi(value[6:])
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, (week-1)*7-(int(jan4.Weekday())+6)%7)
+	if y, w := monday.ISOWeek(); y != year || w != week {
+		return time.Time{}, fmt.Errorf("week %d is not in %d", week, year)
+	}
+	return monday, nil
+}
+
+//go:embed week.go
+var weekGoSource []byte
+
+// ParseWeekInputValueFunc returns a copy of ParseWeekInputValue named name,
+// so generated handlers parse week input values the same way.
+// Package identifiers are mapped to the names file imports them with.
+func ParseWeekInputValueFunc(file *File, name string) (*ast.FuncDecl, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "week.go", weekGoSource, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	packageIdents := make(map[string]string)
+	for _, spec := range f.Imports {
+		pkgPath, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name == nil {
+			packageIdents[path.Base(pkgPath)] = pkgPath
+		}
+	}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "ParseWeekInputValue" {
+			continue
+		}
+		ast.Inspect(fn, func(node ast.Node) bool {
+			if sel, ok := node.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok {
+					if pkgPath, ok := packageIdents[x.Name]; ok {
+						x.Name = file.Import("", pkgPath)
+					}
+				}
+			}
+			return true
+		})
+		fn.Doc = nil
+		fn.Name = ast.NewIdent(name)
+		return fn, nil
+	}
+	return nil, fmt.Errorf("ParseWeekInputValue not found")
+}
@@ -0,0 +1,68 @@
+package source_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/crhntr/muxt/internal/source"
+)
+
+func TestParseWeekInputValue(t *testing.T) {
+	for _, tt := range []struct {
+		Value  string
+		Result time.Time
+		Error  string
+	}{
+		{Value: "2024-W05", Result: time.Date(2024, time.January, 29, 0, 0, 0, 0, time.UTC)},
+		{Value: "2020-W53", Result: time.Date(2020, time.December, 28, 0, 0, 0, 0, time.UTC)},
+		{Value: "2025-W01", Result: time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC)},
+		{Value: "2024-W5", Error: `week "2024-W5" is not formatted like 2006-W01`},
+		{Value: "2024-W05xyz", Error: `week "2024-W05xyz" is not formatted like 2006-W01`},
+		{Value: "+024-W05", Error: `week "+024-W05" is not formatted like 2006-W01`},
+		{Value: "2024-05", Error: `week "2024-05" is not formatted like 2006-W01`},
+		{Value: "2024-W00", Error: `week 0 is not in 2024`},
+		{Value: "2024-W53", Error: `week 53 is not in 2024`},
+	} {
+		t.Run(tt.Value, func(t *testing.T) {
+			result, err := source.ParseWeekInputValue(tt.Value)
+			if tt.Error != "" {
+				require.EqualError(t, err, tt.Error)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.Result, result)
+		})
+	}
+}
+
+func TestParseWeekInputValueFunc(t *testing.T) {
+	pl, err := loadPkg()
+	require.NoError(t, err)
+	wd, err := workingDir()
+	require.NoError(t, err)
+	file, err := source.NewFile(filepath.Join(wd, "tr.go"), fileSet(), pl)
+	require.NoError(t, err)
+
+	fn, err := source.ParseWeekInputValueFunc(file, "parseWeek")
+	require.NoError(t, err)
+	importDecl := &ast.GenDecl{Tok: token.IMPORT}
+	for _, spec := range file.ImportSpecs() {
+		importDecl.Specs = append(importDecl.Specs, spec)
+	}
+	generated := source.Format(&ast.File{Name: ast.NewIdent("week"), Decls: []ast.Decl{importDecl, fn}})
+
+	fSet := token.NewFileSet()
+	f, err := parser.ParseFile(fSet, "week.go", generated, 0)
+	require.NoError(t, err, generated)
+	_, err = (&types.Config{Importer: importer.Default()}).Check("week", fSet, []*ast.File{f}, nil)
+	require.NoError(t, err, generated)
+}